@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+/*
+Files are written atomically: the content goes to a temporary file in the same
+directory, which is renamed over the real one once it's complete. Another
+process reading the file sees either the old content or the new, never part of
+it. The temporary files being written are tracked so that they can be removed
+if scl is interrupted, rather than left behind next to the real ones.
+*/
+var pendingFiles = &tempFiles{names: make(map[string]bool)}
+
+type tempFiles struct {
+	mutex sync.Mutex
+	names map[string]bool
+}
+
+func (t *tempFiles) add(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.names[name] = true
+}
+
+func (t *tempFiles) remove(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.names, name)
+}
+
+// removeAll deletes every temporary file still being written. The lock is
+// kept, so that no more are started before the process exits.
+func (t *tempFiles) removeAll() {
+	t.mutex.Lock()
+
+	for name := range t.names {
+		os.Remove(name)
+	}
+}
+
+// writeFileAtomically replaces the content of a file with a temporary file and
+// a rename, so that the file is never left partly written.
+func writeFileAtomically(fileName string, content []byte, mode os.FileMode) (err error) {
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	pendingFiles.add(tmp.Name())
+	defer pendingFiles.remove(tmp.Name())
+
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// cleanUpOnSignal removes any temporary files being written when scl is
+// interrupted or terminated, then exits with the conventional status for the
+// signal.
+func cleanUpOnSignal() {
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		s := <-signals
+
+		pendingFiles.removeAll()
+
+		fmt.Fprint(os.Stderr, localise(msgStopped, s))
+
+		status := 1
+
+		if n, ok := s.(syscall.Signal); ok {
+			status = 128 + int(n)
+		}
+
+		os.Exit(status)
+	}()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// changedFiles lists the files that differ from the given git ref, including
+// uncommitted changes and untracked files. The paths are absolute.
+func changedFiles(ref string) (map[string]bool, error) {
+
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := gitOutput("diff", "--name-only", ref, "--")
+
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := gitOutput("ls-files", "--others", "--exclude-standard", "--full-name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+
+	for _, path := range append(strings.Split(diff, "\n"), strings.Split(untracked, "\n")...) {
+		if path != "" {
+			changed[filepath.Join(root, filepath.FromSlash(path))] = true
+		}
+	}
+
+	return changed, nil
+}
+
+func gitOutput(args ...string) (string, error) {
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+/*
+affected reports whether a test case needs to be run, given the files that
+have changed: that is, whether the test file, its expected output or anything
+it includes has changed. If the includes can't be worked out without running
+the test, it's assumed to be affected.
+*/
+func (r testRunner) affected(tc testCase, changed map[string]bool) bool {
+
+	base := strings.TrimSuffix(tc.fileName, ".scl")
+	inputs := []string{tc.fileName, base + ".hcl", base + ".json"}
+
+	parser, err := r.parser()
+
+	if err != nil {
+		return true
+	}
+
+	includes, err := parser.Includes(tc.fileName)
+
+	if err != nil {
+		return true
+	}
+
+	for _, input := range append(inputs, includes...) {
+
+		path, err := filepath.Abs(input)
+
+		if err != nil || changed[path] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aryann/difflib"
+	"github.com/hashicorp/hcl"
+)
+
+var newlineMatcher = regexp.MustCompile("\n\n")
+
+// An outputComparison compares the expected output of a test with the actual
+// output, optionally ignoring insignificant differences.
+type outputComparison struct {
+	ignoreWhitespace bool
+	ignoreBlankLines bool
+	ignoreKeyOrder   bool
+	semantic         bool
+}
+
+/*
+compare reports whether the expected and actual outputs match. The line diff
+between them is always returned so that failures can be shown, even when the
+outputs are compared structurally.
+*/
+func (c outputComparison) compare(expected, actual string) (ok bool, diff []difflib.DiffRecord, err error) {
+
+	if c.semantic {
+		return semanticallyEqual(expected, actual)
+	}
+
+	expectedLines := c.lines(strings.TrimSuffix(newlineMatcher.ReplaceAllString(expected, "\n"), "\n"))
+	actualLines := c.lines(actual)
+
+	diff = difflib.Diff(expectedLines, actualLines)
+
+	if c.ignoreKeyOrder {
+		ok, err = structurallyEqual(expected, actual)
+		return
+	}
+
+	ok = true
+
+	for _, d := range diff {
+		if d.Delta != difflib.Common {
+			ok = false
+		}
+	}
+
+	return
+}
+
+func (c outputComparison) lines(s string) (lines []string) {
+
+	for _, line := range strings.Split(s, "\n") {
+
+		if c.ignoreWhitespace {
+			line = strings.Join(strings.Fields(line), " ")
+		}
+
+		if c.ignoreBlankLines && strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return
+}
+
+// structurallyEqual decodes two HCL documents and compares the results, so
+// the order of keys within an object doesn't matter.
+func structurallyEqual(expected, actual string) (bool, error) {
+
+	e, err := decodeHCL(expected)
+
+	if err != nil {
+		return false, err
+	}
+
+	a, err := decodeHCL(actual)
+
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(e, a), nil
+}
+
+/*
+semanticallyEqual decodes two HCL documents into a canonical form, where keys
+and repeated blocks are sorted, and compares them. Lists of values keep their
+order, since that's usually significant. The diff is between the canonical
+forms, written as indented JSON, so it only shows differences that matter.
+*/
+func semanticallyEqual(expected, actual string) (ok bool, diff []difflib.DiffRecord, err error) {
+
+	e, err := canonicalJSON(expected)
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	a, err := canonicalJSON(actual)
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	diff = difflib.Diff(strings.Split(e, "\n"), strings.Split(a, "\n"))
+
+	return e == a, diff, nil
+}
+
+func decodeHCL(document string) (v interface{}, err error) {
+	err = hcl.Decode(&v, document)
+	return
+}
+
+// canonicalJSON decodes an HCL document and writes its canonical form as
+// indented JSON. Maps are always encoded with sorted keys.
+func canonicalJSON(document string) (string, error) {
+
+	v, err := decodeHCL(document)
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.MarshalIndent(canonicalise(v), "", "  ")
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+func canonicalise(v interface{}) interface{} {
+
+	switch value := v.(type) {
+
+	case map[string]interface{}:
+
+		result := make(map[string]interface{}, len(value))
+
+		for k, item := range value {
+			result[k] = canonicalise(item)
+		}
+
+		return result
+
+	case []map[string]interface{}:
+
+		// Repeated blocks are sorted by their content
+		type block struct {
+			key   string
+			value interface{}
+		}
+
+		blocks := make([]block, len(value))
+
+		for i, item := range value {
+			canonical := canonicalise(item)
+			key, _ := json.Marshal(canonical)
+			blocks[i] = block{string(key), canonical}
+		}
+
+		sort.SliceStable(blocks, func(i, j int) bool {
+			return blocks[i].key < blocks[j].key
+		})
+
+		result := make([]interface{}, len(blocks))
+
+		for i, b := range blocks {
+			result[i] = b.value
+		}
+
+		return result
+
+	case []interface{}:
+
+		result := make([]interface{}, len(value))
+
+		for i, item := range value {
+			result[i] = canonicalise(item)
+		}
+
+		return result
+	}
+
+	return v
+}
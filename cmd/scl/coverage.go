@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/homemade/scl"
+)
+
+// A coverageReport merges the coverage of every test case that's run. Blocks
+// in the test files themselves aren't counted, since it's the libraries they
+// exercise that are of interest.
+type coverageReport struct {
+	mutex  sync.Mutex
+	blocks map[string]*scl.CoverageBlock
+}
+
+func newCoverageReport() *coverageReport {
+	return &coverageReport{blocks: make(map[string]*scl.CoverageBlock)}
+}
+
+func (c *coverageReport) add(tc testCase, blocks scl.CoverageBlocks) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, b := range blocks {
+
+		if filepath.Clean(b.File) == filepath.Clean(tc.fileName) {
+			continue
+		}
+
+		c.merge(b)
+	}
+}
+
+func (c *coverageReport) merge(b scl.CoverageBlock) {
+
+	b.File = filepath.Clean(b.File)
+	key := fmt.Sprintf("%s:%s:%d", b.Kind, b.File, b.Line)
+
+	if existing, ok := c.blocks[key]; ok {
+		existing.Count += b.Count
+		return
+	}
+
+	c.blocks[key] = &b
+}
+
+/*
+seed adds every mixin and include in the .scl files under the library roots to
+the report, unused, so that files no test reaches count as 0% covered rather
+than being left out. The test files themselves, and the tests directories of
+libraries, are skipped.
+*/
+func (c *coverageReport) seed(parser scl.Parser, roots []string, testFiles []string) error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	isTest := make(map[string]bool)
+
+	for _, fileName := range testFiles {
+		isTest[filepath.Clean(fileName)] = true
+	}
+
+	for _, root := range roots {
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+
+				if path != root && (info.Name() == "tests" || strings.HasPrefix(info.Name(), ".")) {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if filepath.Ext(path) != ".scl" || isTest[filepath.Clean(path)] {
+				return nil
+			}
+
+			statements, err := parser.Statements(path)
+
+			if err != nil {
+				return err
+			}
+
+			for _, b := range statements {
+				c.merge(b)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *coverageReport) sorted() scl.CoverageBlocks {
+
+	blocks := make(scl.CoverageBlocks, 0, len(c.blocks))
+
+	for _, b := range c.blocks {
+		blocks = append(blocks, *b)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+
+		if blocks[i].File != blocks[j].File {
+			return blocks[i].File < blocks[j].File
+		}
+
+		return blocks[i].Line < blocks[j].Line
+	})
+
+	return blocks
+}
+
+type coverageFile struct {
+	Name    string
+	Blocks  scl.CoverageBlocks
+	Covered int
+}
+
+func (f coverageFile) Percent() float64 {
+
+	if len(f.Blocks) == 0 {
+		return 100
+	}
+
+	return float64(f.Covered) / float64(len(f.Blocks)) * 100
+}
+
+func (c *coverageReport) files() (files []coverageFile) {
+
+	for _, b := range c.sorted() {
+
+		if len(files) == 0 || files[len(files)-1].Name != b.File {
+			files = append(files, coverageFile{Name: b.File})
+		}
+
+		f := &files[len(files)-1]
+		f.Blocks = append(f.Blocks, b)
+
+		if b.Count > 0 {
+			f.Covered++
+		}
+	}
+
+	return
+}
+
+// writeSummary prints the coverage of each file, followed by a list of the
+// mixins and includes which no test used.
+func (c *coverageReport) writeSummary(w io.Writer) {
+
+	files := c.files()
+
+	if len(files) == 0 {
+		fmt.Fprint(w, localise(msgCoverageNone))
+		return
+	}
+
+	total, covered := 0, 0
+
+	fmt.Fprintln(w)
+
+	for _, f := range files {
+		fmt.Fprint(w, localise(msgCoverageFile, f.Percent(), len(f.Blocks), f.Name))
+		total += len(f.Blocks)
+		covered += f.Covered
+	}
+
+	fmt.Fprint(w, localise(msgCoverageTotal, float64(covered)/float64(total)*100, total))
+
+	if covered == total {
+		return
+	}
+
+	fmt.Fprint(w, localise(msgNotCovered))
+
+	for _, f := range files {
+		for _, b := range f.Blocks {
+			if b.Count == 0 {
+				fmt.Fprintf(w, "\t%s:%d\t%s %s\n", b.File, b.Line, b.Kind, b.Name)
+			}
+		}
+	}
+}
+
+type coverageLine struct {
+	Number int
+	Text   string
+	Block  *scl.CoverageBlock
+}
+
+type coverageSource struct {
+	coverageFile
+	Lines []coverageLine
+}
+
+// writeHTML writes a page showing the source of each library, with the
+// mixins and includes highlighted according to whether they were used.
+func (c *coverageReport) writeHTML(fileName string) error {
+
+	var sources []coverageSource
+
+	for _, f := range c.files() {
+
+		source := coverageSource{coverageFile: f}
+		lines := make(map[int]*scl.CoverageBlock)
+
+		for i := range f.Blocks {
+			lines[f.Blocks[i].Line] = &f.Blocks[i]
+		}
+
+		in, err := os.Open(f.Name)
+
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(in)
+
+		for n := 1; scanner.Scan(); n++ {
+			source.Lines = append(source.Lines, coverageLine{
+				Number: n,
+				Text:   scanner.Text(),
+				Block:  lines[n],
+			})
+		}
+
+		in.Close()
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		sources = append(sources, source)
+	}
+
+	out, err := os.Create(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	return coverageTemplate.Execute(out, sources)
+}
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SCL coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { background: #f8f8f8; padding: 1em; }
+.line { display: block; }
+.number { color: #999; display: inline-block; width: 4em; }
+.covered { background: #d6f5d6; }
+.uncovered { background: #f8d0d0; }
+.count { color: #666; float: right; }
+</style>
+</head>
+<body>
+<h1>SCL coverage</h1>
+<ul>
+{{range .}}<li><a href="#{{.Name}}">{{.Name}}</a> ({{printf "%.1f" .Percent}}%)</li>
+{{end}}</ul>
+{{range .}}<h2 id="{{.Name}}">{{.Name}}</h2>
+<pre>{{range .Lines}}<span class="line{{if .Block}}{{if .Block.Count}} covered{{else}} uncovered{{end}}{{end}}"><span class="number">{{.Number}}</span>{{.Text}}{{if .Block}}<span class="count">{{.Block.Count}}</span>{{end}}</span>{{end}}</pre>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/vcs"
+)
+
+/*
+A dependency is a library to fetch, as given on the command line:
+
+	host/org/repo[//subdir][@version]
+
+The optional subdirectory limits the files checked out to that directory of
+the repository, and the optional version is a tag, branch or commit to check
+out instead of the default branch.
+*/
+type dependency struct {
+	name    string
+	subdir  string
+	version string
+}
+
+func parseDependency(arg string) (d dependency) {
+
+	// The scheme is left to the VCS, so any given is dropped
+	if i := strings.Index(arg, "://"); i >= 0 {
+		arg = arg[i+3:]
+	}
+
+	if i := strings.LastIndex(arg, "@"); i > strings.LastIndex(arg, "/") {
+		arg, d.version = arg[:i], arg[i+1:]
+	}
+
+	if i := strings.Index(arg, "//"); i >= 0 {
+		arg, d.subdir = arg[:i], strings.Trim(arg[i+2:], "/")
+	}
+
+	d.name = strings.TrimSuffix(arg, "/")
+
+	return
+}
+
+func (d dependency) String() string {
+
+	s := d.name
+
+	if d.subdir != "" {
+		s += "//" + d.subdir
+	}
+
+	if d.version != "" {
+		s += "@" + d.version
+	}
+
+	return s
+}
+
+/*
+get fetches a dependency that isn't present locally. It's fetched into a
+temporary directory next to its final location, and only moved into place once
+it has been fetched and checked out completely, so that an attempt that fails
+part-way through never leaves a partial checkout for the next one to trip over.
+*/
+func (d dependency) get(ctx context.Context, repo vcs.Repo) error {
+
+	local := repo.LocalPath()
+
+	tmp, err := ioutil.TempDir(filepath.Dir(local), "."+filepath.Base(local)+".fetch")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmp)
+
+	checkout := filepath.Join(tmp, filepath.Base(local))
+
+	if d.subdir != "" {
+		if err := sparseGet(ctx, repo.Vcs(), repo.Remote(), checkout, d.subdir); err != nil {
+			return err
+		}
+	} else if err := runVCS(ctx, tmp, repo.Vcs(), vcsGet(repo.Vcs(), repo.Remote(), filepath.Base(checkout))...); err != nil {
+		return fmt.Errorf("Can't clone %s: %s", repo.Remote(), err)
+	}
+
+	if d.version != "" {
+		if err := checkoutVersion(ctx, repo.Vcs(), checkout, d.version); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(checkout, local)
+}
+
+// update brings a dependency that's already present up to date.
+func (d dependency) update(ctx context.Context, repo vcs.Repo) error {
+
+	for _, args := range vcsUpdate(ctx, repo) {
+		if err := runVCS(ctx, repo.LocalPath(), repo.Vcs(), args...); err != nil {
+			return fmt.Errorf("Can't update %s: %s", repo.Remote(), err)
+		}
+	}
+
+	if d.version != "" {
+		return checkoutVersion(ctx, repo.Vcs(), repo.LocalPath(), d.version)
+	}
+
+	return nil
+}
+
+// checkoutVersion checks out a tag, branch or commit of the repository in a
+// directory.
+func checkoutVersion(ctx context.Context, t vcs.Type, dir, version string) error {
+
+	args := []string{"update", "-r", version}
+
+	if t == vcs.Git {
+		args = []string{"checkout", version}
+	}
+
+	if err := runVCS(ctx, dir, t, args...); err != nil {
+		return fmt.Errorf("Can't check out %s: %s", version, err)
+	}
+
+	return nil
+}
+
+// vcsGet returns the arguments that clone a repository into a new directory.
+func vcsGet(t vcs.Type, remote, local string) []string {
+
+	switch t {
+	case vcs.Git:
+		return []string{"clone", "--recursive", remote, local}
+	case vcs.Svn:
+		return []string{"checkout", remote, local}
+	case vcs.Bzr:
+		return []string{"branch", remote, local}
+	default:
+		return []string{"clone", remote, local}
+	}
+}
+
+// vcsUpdate returns the commands that bring a repository up to date. A git
+// repository with a version checked out has no branch to pull, so its tags
+// and branches are only fetched.
+func vcsUpdate(ctx context.Context, repo vcs.Repo) [][]string {
+
+	switch repo.Vcs() {
+	case vcs.Git:
+
+		if runVCS(ctx, repo.LocalPath(), vcs.Git, "symbolic-ref", "-q", "HEAD") != nil {
+			return [][]string{{"fetch", "--tags", "origin"}}
+		}
+
+		return [][]string{{"fetch", "--tags", "origin"}, {"pull"}}
+
+	case vcs.Hg:
+		return [][]string{{"pull"}, {"update"}}
+	case vcs.Bzr:
+		return [][]string{{"pull"}}
+	default:
+		return [][]string{{"update"}}
+	}
+}
+
+// runVCS runs a VCS command in a directory. The command is killed if the
+// context is done before it finishes.
+func runVCS(ctx context.Context, dir string, t vcs.Type, args ...string) error {
+
+	cmd := exec.CommandContext(ctx, string(t), args...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if message := strings.TrimSpace(string(out)); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+/*
+sparseGet clones a git repository without checking out any files, and then
+checks out only the given subdirectory. Blobs outside the subdirectory aren't
+downloaded at all, which keeps fetches of large monorepos fast.
+*/
+func sparseGet(ctx context.Context, t vcs.Type, remote, checkout, subdir string) error {
+
+	if t != vcs.Git {
+		return fmt.Errorf("Subdirectory checkouts aren't supported for %s repositories", t)
+	}
+
+	if err := runVCS(ctx, filepath.Dir(checkout), vcs.Git, "clone", "--filter=blob:none", "--no-checkout", remote, filepath.Base(checkout)); err != nil {
+		return fmt.Errorf("Can't clone %s: %s", remote, err)
+	}
+
+	for _, args := range [][]string{
+		{"sparse-checkout", "init", "--cone"},
+		{"sparse-checkout", "set", subdir},
+		{"checkout"},
+	} {
+		if err := runVCS(ctx, checkout, vcs.Git, args...); err != nil {
+			return fmt.Errorf("Can't check out %s: %s", subdir, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ADependencyCanBeParsed(t *testing.T) {
+
+	for cycle, input := range []struct {
+		arg      string
+		expected dependency
+	}{
+		{"github.com/org/repo", dependency{name: "github.com/org/repo"}},
+		{"github.com/org/repo/", dependency{name: "github.com/org/repo"}},
+		{"github.com/org/repo//libs/net", dependency{name: "github.com/org/repo", subdir: "libs/net"}},
+		{"github.com/org/repo//libs/net/", dependency{name: "github.com/org/repo", subdir: "libs/net"}},
+		{"github.com/org/repo@v1.2.0", dependency{name: "github.com/org/repo", version: "v1.2.0"}},
+		{"github.com/org/repo//libs/net@v1.2.0", dependency{name: "github.com/org/repo", subdir: "libs/net", version: "v1.2.0"}},
+		{"https://github.com/org/repo", dependency{name: "github.com/org/repo"}},
+		{"http://github.com/org/repo//libs@main", dependency{name: "github.com/org/repo", subdir: "libs", version: "main"}},
+		{"git://github.com/org/repo", dependency{name: "github.com/org/repo"}},
+		{"git.example.com/user@host/repo", dependency{name: "git.example.com/user@host/repo"}},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		d := parseDependency(input.arg)
+
+		require.Equal(t, input.expected, d)
+		require.Equal(t, d, parseDependency(d.String()))
+	}
+}
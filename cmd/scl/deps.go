@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func depsCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "deps",
+		Brief: "List the files that .scl files include, or that include a file",
+		Usage: `[options] <filename.scl...> | --reverse <filename.scl> [directory...]`,
+		Help:  "List every file that each .scl file includes, directly or through other includes. With --reverse, scan the .scl files in the given directories, or the current directory, and list every one that includes the named file instead, to see what a change to a shared library affects.",
+
+		Flags: append(standardParserParams(),
+			climax.Flag{
+				Name:     "reverse",
+				Short:    "r",
+				Usage:    `--reverse lib/network.scl`,
+				Help:     `List the files that include this file, directly or through other includes`,
+				Variable: true,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			target, reverse := ctx.Get("reverse")
+
+			if len(ctx.Args) == 0 && !reverse {
+				fmt.Fprint(stderr, localise(msgFilenameRequired, "deps"))
+				return 1
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgCreateParser, err.Error()))
+				return 1
+			}
+
+			if !reverse {
+
+				for _, fileName := range ctx.Args {
+
+					includes, err := parser.Includes(fileName)
+
+					if err != nil {
+						fmt.Fprint(stderr, localise(msgListIncludes, err.Error()))
+						return 1
+					}
+
+					fmt.Fprintf(stdout, "%s:\n", fileName)
+
+					for _, include := range includes {
+						fmt.Fprintf(stdout, "\t%s\n", include)
+					}
+				}
+
+				return 0
+			}
+
+			dirs := ctx.Args
+
+			if len(dirs) == 0 {
+				dirs = []string{"."}
+			}
+
+			dependents, errors := reverseDependencies(parser, target, dirs)
+
+			for _, e := range errors {
+				fmt.Fprint(stderr, localise(msgWarning, e))
+			}
+
+			for _, fileName := range dependents {
+				fmt.Fprintln(stdout, fileName)
+			}
+
+			fmt.Fprint(stderr, localise(msgDependents, len(dependents), target))
+
+			return 0
+		},
+	}
+}
+
+// reverseDependencies finds the .scl files in the given directories which
+// include the target, directly or through other includes. Files whose
+// includes can't be followed are skipped and reported as errors.
+func reverseDependencies(p scl.Parser, target string, dirs []string) (dependents []string, errors []string) {
+
+	target = absolutePath(target)
+
+	for _, dir := range dirs {
+
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+
+				if path != dir && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if filepath.Ext(path) != ".scl" || absolutePath(path) == target {
+				return nil
+			}
+
+			includes, err := p.Includes(path)
+
+			if err != nil {
+				errors = append(errors, err.Error())
+				return nil
+			}
+
+			for _, include := range includes {
+				if absolutePath(include) == target {
+					dependents = append(dependents, path)
+					break
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	sort.Strings(dependents)
+
+	return
+}
+
+func absolutePath(path string) string {
+
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
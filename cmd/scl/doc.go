@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func docCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "doc",
+		Brief: "Show the documentation of .scl files and libraries",
+		Usage: `[options] [filename.scl...]`,
+		Help:  "Print the metadata and documented mixins in each .scl file. With --site, generate a static HTML site documenting every library in the vendor directory and include paths instead, with each library's README and links from mentions of mixins to their definitions.",
+
+		Flags: append(standardParserParams(),
+			climax.Flag{
+				Name:     "site",
+				Usage:    `--site <directory>`,
+				Help:     `Write an HTML site documenting every library to the directory`,
+				Variable: true,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			site, generateSite := ctx.Get("site")
+
+			if len(ctx.Args) == 0 && !generateSite {
+				fmt.Fprint(stderr, localise(msgFilenameRequired, "doc"))
+				return 1
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgCreateParser, err.Error()))
+				return 1
+			}
+
+			if generateSite {
+
+				index, err := buildLibraryIndex(parser, libraryRoots(includePaths))
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgIndexLibraries, err.Error()))
+					return 1
+				}
+
+				for _, e := range index.errors() {
+					fmt.Fprint(stderr, localise(msgWarning, e))
+				}
+
+				if err := writeDocSite(site, index); err != nil {
+					fmt.Fprint(stderr, localise(msgWriteSite, err.Error()))
+					return 1
+				}
+
+				fmt.Fprint(stdout, localise(msgDocumented, len(index.libraries), site))
+				return 0
+			}
+
+			for _, fileName := range ctx.Args {
+
+				metadata, err := parser.Metadata(fileName)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgReadMetadata, err.Error()))
+					return 1
+				}
+
+				writeFileMetadata(stdout, metadata)
+
+				docs, err := parser.Documentation(fileName)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgReadDocumentation, err.Error()))
+					return 1
+				}
+
+				writeMixinDocs(stdout, docs, 0)
+			}
+
+			return 0
+		},
+	}
+}
+
+func writeFileMetadata(w io.Writer, metadata scl.FileMetadata) {
+
+	if metadata.Empty() {
+		return
+	}
+
+	fmt.Fprintf(w, "%s\n", metadata.File)
+
+	for _, field := range []struct{ name, value string }{
+		{"Description", metadata.Description},
+		{"Author", metadata.Author},
+		{"Version", metadata.Version},
+		{"Tags", strings.Join(metadata.Tags, ", ")},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(w, "    %s: %s\n", field.name, field.value)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
+
+func writeMixinDocs(w io.Writer, docs scl.MixinDocs, depth int) {
+
+	indent := strings.Repeat("    ", depth)
+
+	for _, d := range docs {
+
+		fmt.Fprintf(w, "%s%s\t%s\n", indent, d.Signature, d.Reference)
+
+		if d.Docs != "" {
+			for _, line := range strings.Split(strings.TrimSpace(d.Docs), "\n") {
+				fmt.Fprintf(w, "%s    %s\n", indent, line)
+			}
+		}
+
+		fmt.Fprintln(w)
+
+		writeMixinDocs(w, d.Children, depth+1)
+	}
+}
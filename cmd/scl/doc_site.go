@@ -0,0 +1,195 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/homemade/scl"
+)
+
+var mixinReferenceMatcher = regexp.MustCompile(`\b([a-zA-Z_][a-zA-Z0-9_]*)\(`)
+var anchorCleaner = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// A docSite renders a libraryIndex as one page per library, plus an index
+// page listing them all.
+type docSite struct {
+	index *libraryIndex
+
+	// The definitions of each mixin name, in index order
+	definitions map[string][]mixinLink
+}
+
+// A docSection is a list of mixins, with the library they belong to so that
+// links can prefer its own definitions.
+type docSection struct {
+	Library *indexedLibrary
+	Mixins  scl.MixinDocs
+}
+
+type mixinLink struct {
+	library *indexedLibrary
+	href    string
+}
+
+func writeDocSite(dir string, index *libraryIndex) error {
+
+	site := docSite{
+		index:       index,
+		definitions: make(map[string][]mixinLink),
+	}
+
+	index.walkMixins(func(library *indexedLibrary, _ indexedFile, d scl.MixinDoc) {
+		site.definitions[d.Name] = append(site.definitions[d.Name], mixinLink{
+			library: library,
+			href:    libraryPage(library.Name) + "#" + mixinAnchor(d),
+		})
+	})
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := site.write(filepath.Join(dir, "index.html"), "index", index.libraries); err != nil {
+		return err
+	}
+
+	for _, library := range index.libraries {
+		if err := site.write(filepath.Join(dir, libraryPage(library.Name)), "library", library); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s docSite) write(path, name string, data interface{}) error {
+
+	t, err := s.templates()
+
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	return t.ExecuteTemplate(out, name, data)
+}
+
+func (s docSite) templates() (*template.Template, error) {
+
+	return template.New("site").Funcs(template.FuncMap{
+		"page":   libraryPage,
+		"anchor": mixinAnchor,
+		"link":   s.link,
+		"section": func(library *indexedLibrary, mixins scl.MixinDocs) docSection {
+			return docSection{library, mixins}
+		},
+	}).Parse(docSiteTemplates)
+}
+
+// link escapes text and turns each mention of a known mixin, written as a
+// call, into a link to its definition. Definitions in the same library are
+// preferred to those in others.
+func (s docSite) link(library *indexedLibrary, text string) template.HTML {
+
+	var out []string
+	last := 0
+
+	for _, match := range mixinReferenceMatcher.FindAllStringSubmatchIndex(text, -1) {
+
+		name := text[match[2]:match[3]]
+		links := s.definitions[name]
+
+		if len(links) == 0 {
+			continue
+		}
+
+		href := links[0].href
+
+		for _, l := range links {
+			if l.library == library {
+				href = l.href
+				break
+			}
+		}
+
+		out = append(out,
+			template.HTMLEscapeString(text[last:match[2]]),
+			`<a href="`+template.HTMLEscapeString(href)+`">`+template.HTMLEscapeString(name)+`</a>`,
+		)
+
+		last = match[3]
+	}
+
+	out = append(out, template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(strings.Join(out, ""))
+}
+
+func libraryPage(name string) string {
+
+	if name == "." {
+		return "library.html"
+	}
+
+	return "library-" + strings.Replace(name, "/", "--", -1) + ".html"
+}
+
+func mixinAnchor(d scl.MixinDoc) string {
+	return "mixin-" + strings.Trim(anchorCleaner.ReplaceAllString(d.Name+"-"+d.Reference, "-"), "-")
+}
+
+const docSiteTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; }
+pre { background: #f8f8f8; padding: 1em; white-space: pre-wrap; }
+.reference { color: #999; font-size: small; }
+.children { margin-left: 2em; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "header" "SCL libraries"}}<h1>SCL libraries</h1>
+<ul>
+{{range .}}<li><a href="{{page .Name}}">{{.Name}}</a>{{if .Description}} &mdash; {{.Description}}{{end}}</li>
+{{end}}</ul>
+{{template "footer"}}{{end}}
+
+{{define "mixins"}}{{$library := .Library}}{{range .Mixins}}<div class="mixin">
+<h3 id="{{anchor .}}"><code>{{.Signature}}</code> <span class="reference">{{.Reference}}</span></h3>
+{{if .Docs}}<pre>{{link $library .Docs}}</pre>{{end}}
+{{if .Children}}<div class="children">{{template "mixins" (section $library .Children)}}</div>{{end}}
+</div>
+{{end}}{{end}}
+
+{{define "library"}}{{template "header" .Name}}<p><a href="index.html">All libraries</a></p>
+<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Readme}}<pre>{{link . .Readme}}</pre>{{end}}
+{{$library := .}}{{range .Files}}<h2>{{.Name}}</h2>
+{{with .Metadata}}{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if or .Version .Author}}<p class="reference">{{if .Version}}Version {{.Version}}{{end}}{{if and .Version .Author}}, {{end}}{{if .Author}}by {{.Author}}{{end}}</p>{{end}}
+{{if .Tags}}<p class="reference">Tags: {{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</p>{{end}}{{end}}
+{{if .Error}}<p>Unable to read documentation: {{.Error}}</p>{{end}}
+{{template "mixins" (section $library .Mixins)}}{{end}}
+{{template "footer"}}{{end}}
+`
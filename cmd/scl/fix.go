@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aryann/difflib"
+	"github.com/homemade/scl"
+	"github.com/tucnak/climax"
+)
+
+func fixCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "fix",
+		Brief: "Tidy .scl files automatically",
+		Usage: `[options] <unused-includes|include-paths> [file.scl|dir/...]`,
+		Help:  "Apply a fix to .scl files, given as files or directories, or every file under the current directory by default.\n\nunused-includes removes the arguments to include statements which provide nothing the file uses and write no output, and the statements left with none.\n\ninclude-paths changes the file names given to include statements which are, or are within, the --from path to start with the --to path instead, for when a library moves.",
+
+		Flags: append(standardParserParams(),
+			climax.Flag{
+				Name:  "dry-run",
+				Short: "n",
+				Usage: `--dry-run`,
+				Help:  `Print a diff of the changes instead of making them`,
+			},
+			climax.Flag{
+				Name:     "from",
+				Usage:    `--from vendor/old/path`,
+				Help:     `The path include-paths moves includes from`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "to",
+				Usage:    `--to vendor/new/path`,
+				Help:     `The path include-paths moves includes to`,
+				Variable: true,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) == 0 {
+				fmt.Fprint(stderr, localise(msgFixRequired))
+				return 1
+			}
+
+			patterns := ctx.Args[1:]
+
+			if len(patterns) == 0 {
+				patterns = []string{"./..."}
+			}
+
+			files, err := sourceFiles(patterns)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgListFiles, err.Error()))
+				return 1
+			}
+
+			var (
+				fix    func(fileName string, src []byte) ([]byte, error)
+				params paramSlice
+			)
+
+			switch ctx.Args[0] {
+
+			case "unused-includes":
+
+				var (
+					environment  scl.Environment
+					includePaths []string
+				)
+
+				params, environment, includePaths, err = parserParams(ctx)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+					return 1
+				}
+
+				workspace, err := parserWorkspace(ctx)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+					return 1
+				}
+
+				caseInsensitive, err := includesIgnoreCase(ctx)
+
+				if err != nil {
+					fmt.Fprintf(stderr, "%s\n", err.Error())
+					return 1
+				}
+
+				fix = func(fileName string, src []byte) ([]byte, error) {
+					return fixUnusedIncludes(params, environment, includePaths, workspace, caseInsensitive, fileName, src)
+				}
+
+			case "include-paths":
+
+				from, _ := ctx.Get("from")
+				to, _ := ctx.Get("to")
+
+				if from == "" || to == "" {
+					fmt.Fprint(stderr, localise(msgFromToRequired))
+					return 1
+				}
+
+				fix = func(fileName string, src []byte) ([]byte, error) {
+					out, _, err := scl.RewriteIncludePaths(src, fileName, from, to)
+					return out, err
+				}
+
+			default:
+				fmt.Fprint(stderr, localise(msgUnknownFix, ctx.Args[0]))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			for _, fileName := range files {
+
+				src, err := ioutil.ReadFile(fileName)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgReadFile, err.Error()))
+					return 1
+				}
+
+				out, err := fix(fileName, src)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgFixFile, fileName, err.Error()))
+					return 1
+				}
+
+				if string(out) == string(src) {
+					continue
+				}
+
+				if ctx.Is("dry-run") {
+					writeSourceDiff(stdout, fileName, src, out)
+					continue
+				}
+
+				if err := writeSourceFile(fileName, out); err != nil {
+					fmt.Fprint(stderr, localise(msgWriteFile, err.Error()))
+					return 1
+				}
+
+				fmt.Fprint(stdout, localise(msgFixed, fileName))
+			}
+
+			return 0
+		},
+	}
+}
+
+// writeSourceDiff prints the lines that differ between the old and new content
+// of a file.
+func writeSourceDiff(w io.Writer, fileName string, before, after []byte) {
+
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", fileName, fileName)
+
+	for _, d := range difflib.Diff(strings.Split(string(before), "\n"), strings.Split(string(after), "\n")) {
+		if d.Delta != difflib.Common {
+			fmt.Fprintln(w, d.String())
+		}
+	}
+
+	fmt.Fprintln(w)
+}
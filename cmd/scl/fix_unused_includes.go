@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/homemade/scl"
+)
+
+func fixUnusedIncludes(params paramSlice, environment scl.Environment, includePaths []string, workspace scl.Workspace, caseInsensitive bool, fileName string, src []byte) ([]byte, error) {
+
+	parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+	if err != nil {
+		return nil, err
+	}
+
+	unused, err := parser.UnusedIncludes(fileName)
+
+	if err != nil || len(unused) == 0 {
+		return src, err
+	}
+
+	return scl.RemoveIncludes(src, fileName, unused)
+}
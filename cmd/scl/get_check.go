@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Masterminds/vcs"
+)
+
+// checkDependency compares the version of a dependency that's checked out
+// locally with the tags available upstream, without changing anything. It
+// returns the newest upstream tag, or an empty string if there's nothing newer.
+// If compatibleOnly is set, only tags with the same major version are
+// considered.
+func checkDependency(repo vcs.Repo, compatibleOnly bool) (current, latest string, err error) {
+
+	if repo.Vcs() != vcs.Git {
+		return "", "", fmt.Errorf("Checking for updates isn't supported for %s repositories", repo.Vcs())
+	}
+
+	commit, err := repo.Version()
+
+	if err != nil {
+		return "", "", fmt.Errorf("Can't read local version: %s", err)
+	}
+
+	current = commit
+
+	localTags, err := repo.TagsFromCommit(commit)
+
+	if err != nil {
+		return "", "", fmt.Errorf("Can't read local tags: %s", err)
+	}
+
+	var currentVersion semver
+	hasVersion := false
+
+	for _, tag := range localTags {
+		if v, ok := parseSemver(tag); ok && (!hasVersion || currentVersion.less(v)) {
+			current, currentVersion, hasVersion = tag, v, true
+		}
+	}
+
+	out, err := repo.RunFromDir("git", "ls-remote", "--tags", repo.Remote())
+
+	if err != nil {
+		return current, "", fmt.Errorf("Can't list remote tags: %s", strings.TrimSpace(string(out)))
+	}
+
+	var latestVersion semver
+
+	for _, line := range strings.Split(string(out), "\n") {
+
+		fields := strings.Fields(line)
+
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "refs/tags/") || strings.HasSuffix(fields[1], "^{}") {
+			continue
+		}
+
+		tag := strings.TrimPrefix(fields[1], "refs/tags/")
+		v, ok := parseSemver(tag)
+
+		switch {
+		case !ok, v.pre != "":
+			continue
+		case hasVersion && !currentVersion.less(v):
+			continue
+		case hasVersion && compatibleOnly && v.major != currentVersion.major:
+			continue
+		case latest != "" && !latestVersion.less(v):
+			continue
+		}
+
+		latest, latestVersion = tag, v
+	}
+
+	return current, latest, nil
+}
+
+func checkDependencies(vendorDir string, mirrors mirrorMap, deps []string, compatibleOnly, verbose bool, stdout, stderr io.Writer) int {
+
+	updates, failures := 0, 0
+
+	for _, arg := range deps {
+
+		dep := parseDependency(arg)
+		repo, err := dependencyRepo(vendorDir, mirrors, dep.name)
+
+		if err != nil {
+			fmt.Fprint(stderr, localise(msgCreateRepo, dep, err.Error()))
+			failures++
+			continue
+		}
+
+		if !repo.CheckLocal() {
+			fmt.Fprint(stderr, localise(msgNotPresent, dep))
+			failures++
+			continue
+		}
+
+		current, latest, err := checkDependency(repo, compatibleOnly)
+
+		if err != nil {
+			fmt.Fprint(stderr, localise(msgCheckDependency, dep, err.Error()))
+			failures++
+			continue
+		}
+
+		if latest == "" {
+			if verbose {
+				fmt.Fprint(stdout, localise(msgDependencyUpToDate, dep, current))
+			}
+			continue
+		}
+
+		updates++
+		fmt.Fprint(stdout, localise(msgDependencyUpdate, dep, current, latest))
+	}
+
+	if verbose {
+		fmt.Fprint(stdout, localise(msgUpdatesAvailable, updates))
+	}
+
+	if failures > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+// An input is something external that a file read while it was parsed: an
+// environment variable, a param given with --param or --param-file, or a file.
+type input struct {
+	kind string
+	name string
+}
+
+func (i input) String() string {
+	return fmt.Sprintf("%-5s %s", i.kind, i.name)
+}
+
+// An inputReport lists the inputs read while parsing a file.
+type inputReport struct {
+	fileName string
+	inputs   []input
+}
+
+func newInputReport(fileName string, inputs scl.Inputs, environment scl.Environment, params paramSlice) inputReport {
+
+	// Params replace environment variables with the same name
+	set := make(map[string]bool)
+
+	for _, p := range params {
+		set[p.name] = true
+	}
+
+	report := inputReport{fileName: fileName}
+
+	for _, name := range inputs.Params {
+		if _, env := environment.Lookup(name); env && !set[name] {
+			report.inputs = append(report.inputs, input{"env", name})
+		} else {
+			report.inputs = append(report.inputs, input{"param", name})
+		}
+	}
+
+	for _, path := range inputs.Files {
+		if path != fileName {
+			report.inputs = append(report.inputs, input{"file", path})
+		}
+	}
+
+	return report
+}
+
+func (r inputReport) write(w io.Writer) {
+
+	fmt.Fprint(w, localise(msgInputsReadBy, r.fileName))
+
+	if len(r.inputs) == 0 {
+		fmt.Fprint(w, localise(msgNoInputs))
+	}
+
+	for _, i := range r.inputs {
+		fmt.Fprintf(w, "\t%s\n", i)
+	}
+}
+
+// An inputAllowlist holds the patterns of environment variables and files
+// that --hermetic allows. Params given on the command line are always allowed,
+// as they're declared by the caller.
+type inputAllowlist struct {
+	env   []string
+	files []string
+}
+
+func newInputAllowlist(ctx climax.Context) (a inputAllowlist) {
+
+	if patterns, set := ctx.Get("allow-env"); set {
+		a.env = splitPatterns(patterns)
+	}
+
+	if patterns, set := ctx.Get("allow-file"); set {
+		a.files = splitPatterns(patterns)
+	}
+
+	return
+}
+
+func (a inputAllowlist) undeclared(r inputReport) (undeclared []input) {
+
+	for _, i := range r.inputs {
+
+		var patterns []string
+
+		switch i.kind {
+		case "env":
+			patterns = a.env
+		case "file":
+			patterns = a.files
+		default:
+			continue
+		}
+
+		if !matchesAny(patterns, i.name) {
+			undeclared = append(undeclared, i)
+		}
+	}
+
+	return
+}
+
+func splitPatterns(s string) (patterns []string) {
+
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return
+}
+
+func matchesAny(patterns []string, name string) bool {
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/homemade/scl"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TheInputsReportSeparatesTheEnvironmentFromParams(t *testing.T) {
+
+	environment := scl.NewEnvironment([]string{"REGION=eu-west-1", "SIZE=small"})
+	params := paramSlice{{name: "SIZE", value: `"large"`}}
+
+	parser, err := configuredParser(params, environment, nil, scl.Workspace{}, false)
+	require.Nil(t, err)
+	require.Equal(t, environment, parser.Environment())
+
+	report := newInputReport("main.scl", scl.Inputs{Params: []string{"REGION", "SIZE"}}, parser.Environment(), params)
+
+	require.Equal(t, []input{
+		{"env", "REGION"},
+		{"param", "SIZE"},
+	}, report.inputs)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/aryann/difflib"
+	"github.com/hashicorp/hcl"
+)
+
+// renderJSON renders HCL as indented JSON. Blocks become lists of objects,
+// in the same way that they're decoded by the hcl package.
+func renderJSON(hclString string) ([]byte, error) {
+
+	var v interface{}
+
+	if err := hcl.Decode(&v, hclString); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// compareJSON compares expected JSON with the JSON rendering of the actual
+// HCL output. The comparison is of the decoded values, so formatting and key
+// order in the expected file don't matter.
+func compareJSON(expected, actual string) (ok bool, diff []difflib.DiffRecord, err error) {
+
+	rendered, err := renderJSON(actual)
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	var e, a interface{}
+
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		return false, nil, err
+	}
+
+	if err := json.Unmarshal(rendered, &a); err != nil {
+		return false, nil, err
+	}
+
+	// Re-indent the expected JSON so the diff only shows real differences
+	canonical, err := json.MarshalIndent(e, "", "  ")
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	diff = difflib.Diff(strings.Split(string(canonical), "\n"), strings.Split(string(rendered), "\n"))
+
+	return reflect.DeepEqual(e, a), diff, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+
+	"github.com/homemade/scl"
+)
+
+// A libraryIndex holds the documentation of every library found under a set
+// of include paths. Each directory containing .scl files is a library, named
+// by its path relative to the include path it was found in.
+type libraryIndex struct {
+	libraries []*indexedLibrary
+}
+
+type indexedLibrary struct {
+	Name        string
+	Dir         string
+	Description string
+	Readme      string
+	Files       []indexedFile
+}
+
+// An indexedFile holds the documentation and metadata of a file, or the reason
+// it couldn't be read. One broken file doesn't stop the rest of a library being
+// indexed.
+type indexedFile struct {
+	Name     string
+	Metadata scl.FileMetadata
+	Mixins   scl.MixinDocs
+	Error    string
+}
+
+// libraryRoots returns the include paths to index, with the vendor directory
+// in the current directory first if there is one.
+func libraryRoots(includePaths []string) (roots []string) {
+
+	if stat, err := os.Stat("vendor"); err == nil && stat.IsDir() {
+		roots = append(roots, "vendor")
+	}
+
+	return append(roots, includePaths...)
+}
+
+func buildLibraryIndex(p scl.Parser, roots []string) (*libraryIndex, error) {
+
+	index := &libraryIndex{}
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+
+		files := make(map[string][]string)
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+
+				// Tests aren't part of a library's interface
+				if path != root && (info.Name() == "tests" || strings.HasPrefix(info.Name(), ".")) {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if filepath.Ext(path) == ".scl" {
+				files[filepath.Dir(path)] = append(files[filepath.Dir(path)], path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for dir, paths := range files {
+
+			name, err := filepath.Rel(root, dir)
+
+			if err != nil {
+				return nil, err
+			}
+
+			name = filepath.ToSlash(name)
+
+			// A library in more than one include path is only indexed from
+			// the first, as that's the one includes resolve to
+			if seen[name] {
+				continue
+			}
+
+			seen[name] = true
+
+			library, err := indexLibrary(p, name, dir, paths)
+
+			if err != nil {
+				return nil, err
+			}
+
+			index.libraries = append(index.libraries, library)
+		}
+	}
+
+	sort.Slice(index.libraries, func(i, j int) bool {
+		return index.libraries[i].Name < index.libraries[j].Name
+	})
+
+	return index, nil
+}
+
+func indexLibrary(p scl.Parser, name, dir string, paths []string) (*indexedLibrary, error) {
+
+	library := &indexedLibrary{
+		Name:   name,
+		Dir:    dir,
+		Readme: readLibraryReadme(dir),
+	}
+
+	if content, err := ioutil.ReadFile(filepath.Join(dir, "scl.lib")); err == nil {
+
+		metadata := struct {
+			Description string `hcl:"description"`
+		}{}
+
+		if err := hcl.Decode(&metadata, string(content)); err == nil {
+			library.Description = metadata.Description
+		}
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+
+		file := indexedFile{Name: path}
+
+		if metadata, err := p.Metadata(path); err != nil {
+			file.Error = err.Error()
+		} else {
+			file.Metadata = metadata
+		}
+
+		if docs, err := p.Documentation(path); err != nil {
+			file.Error = err.Error()
+		} else {
+			file.Mixins = docs
+		}
+
+		library.Files = append(library.Files, file)
+	}
+
+	return library, nil
+}
+
+// readLibraryReadme returns the content of a library's README, whatever its
+// extension, or an empty string if it doesn't have one.
+func readLibraryReadme(dir string) string {
+
+	entries, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		return ""
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(strings.ToLower(entry.Name()), "readme") {
+			if content, err := ioutil.ReadFile(filepath.Join(dir, entry.Name())); err == nil {
+				return string(content)
+			}
+		}
+	}
+
+	return ""
+}
+
+// errors lists the files that couldn't be indexed, and why.
+func (index *libraryIndex) errors() (errors []string) {
+
+	for _, library := range index.libraries {
+		for _, f := range library.Files {
+			if f.Error != "" {
+				errors = append(errors, f.Error)
+			}
+		}
+	}
+
+	return
+}
+
+// walkMixins calls fn for every mixin in the index, including nested ones,
+// along with the library and file it's declared in.
+func (index *libraryIndex) walkMixins(fn func(library *indexedLibrary, file indexedFile, doc scl.MixinDoc)) {
+
+	var walk func(library *indexedLibrary, file indexedFile, docs scl.MixinDocs)
+
+	walk = func(library *indexedLibrary, file indexedFile, docs scl.MixinDocs) {
+		for _, d := range docs {
+			fn(library, file, d)
+			walk(library, file, d.Children)
+		}
+	}
+
+	for _, library := range index.libraries {
+		for _, f := range library.Files {
+			walk(library, f, f.Mixins)
+		}
+	}
+}
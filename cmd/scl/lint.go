@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func lintCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "lint",
+		Brief: "Check .scl files for common problems",
+		Usage: `[options] <filename.scl...>`,
+		Help:  "Parse each .scl file and report problems that don't stop it from parsing, such as private mixins that are never called or public mixins without documentation.",
+
+		Flags: append(standardParserParams(),
+			climax.Flag{
+				Name:     "config",
+				Short:    "c",
+				Usage:    `--config /path/to/scl.lint`,
+				Help:     `A lint configuration file, which can disable rules and declare new ones. Default is the nearest scl.lint file`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "baseline",
+				Short:    "b",
+				Usage:    `--baseline baseline.json`,
+				Help:     `A file of existing findings to ignore. Only findings which aren't in the baseline cause lint to fail`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "write-baseline",
+				Usage: `--write-baseline`,
+				Help:  `Record the current findings in the --baseline file instead of reporting them`,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) == 0 {
+				fmt.Fprint(stderr, localise(msgFilenameRequired, "lint"))
+				return 1
+			}
+
+			baselineFile, useBaseline := ctx.Get("baseline")
+
+			if ctx.Is("write-baseline") && !useBaseline {
+				fmt.Fprint(stderr, localise(msgBaselineRequired))
+				return 1
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			config, err := lintConfiguration(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadLintConfig, err.Error()))
+				return 1
+			}
+
+			var findings lintFindings
+
+			for _, fileName := range ctx.Args {
+
+				parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgCreateParser, err.Error()))
+					return 1
+				}
+
+				f, err := lintFile(parser, fileName, config.activeRules())
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgLintFile, fileName, err.Error()))
+					return 1
+				}
+
+				findings = append(findings, f...)
+			}
+
+			findings.sort()
+
+			if ctx.Is("write-baseline") {
+
+				if err := findings.write(baselineFile); err != nil {
+					fmt.Fprint(stderr, localise(msgWriteBaseline, err.Error()))
+					return 1
+				}
+
+				fmt.Fprint(stdout, localise(msgRecordedFindings, len(findings), baselineFile))
+				return 0
+			}
+
+			ignored := 0
+
+			if useBaseline {
+
+				baseline, err := loadLintFindings(baselineFile)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgReadBaseline, err.Error()))
+					return 1
+				}
+
+				total := len(findings)
+				findings = findings.without(baseline)
+				ignored = total - len(findings)
+			}
+
+			for _, f := range findings {
+				fmt.Fprintln(stdout, f.String())
+			}
+
+			if ignored > 0 {
+				fmt.Fprint(stdout, localise(msgIgnoredFindings, ignored, baselineFile))
+			}
+
+			if len(findings) > 0 {
+				fmt.Fprint(stderr, localise(msgFailedFindings, len(findings)))
+				return 1
+			}
+
+			return 0
+		},
+	}
+}
+
+// A lintRule checks a file which has already been parsed successfully.
+type lintRule struct {
+	name  string
+	check func(p scl.Parser, fileName string) (lintFindings, error)
+}
+
+var lintRules = []lintRule{
+	{"unused-mixin", lintUnusedMixins},
+	{"undocumented-mixin", lintUndocumentedMixins},
+}
+
+// lintConfiguration loads the lint configuration given on the command line
+// or, failing that, the nearest one to the current directory.
+func lintConfiguration(ctx climax.Context) (lintConfig, error) {
+
+	path, set := ctx.Get("config")
+
+	if !set {
+
+		cwd, err := os.Getwd()
+
+		if err != nil {
+			return lintConfig{}, err
+		}
+
+		if path = findUpwards(cwd, lintConfigFileName); path == "" {
+			return lintConfig{}, nil
+		}
+	}
+
+	return loadLintConfig(path)
+}
+
+func lintFile(p scl.Parser, fileName string, rules []lintRule) (findings lintFindings, err error) {
+
+	if err := p.Parse(fileName); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+
+		f, err := rule.check(p, fileName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range f {
+			f[i].Rule = rule.name
+		}
+
+		findings = append(findings, f...)
+	}
+
+	return
+}
+
+// lintUnusedMixins reports private mixins, whose names start with an
+// underscore, which are declared in the file but never called.
+func lintUnusedMixins(p scl.Parser, fileName string) (findings lintFindings, err error) {
+
+	for _, b := range p.Coverage() {
+
+		if b.Kind != scl.CoverageMixin || b.Count > 0 || !privateMixin(b.Name) {
+			continue
+		}
+
+		if filepath.Clean(b.File) != filepath.Clean(fileName) {
+			continue
+		}
+
+		findings = append(findings, newLintFinding(b.File, b.Line, msgLintUnusedMixin, b.Name))
+	}
+
+	return
+}
+
+// lintUndocumentedMixins reports public mixins without a doc comment. Private
+// mixins aren't part of a library's interface, so they needn't be documented.
+func lintUndocumentedMixins(p scl.Parser, fileName string) (findings lintFindings, err error) {
+
+	docs, err := p.Documentation(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var walk func(docs scl.MixinDocs)
+
+	walk = func(docs scl.MixinDocs) {
+		for _, d := range docs {
+
+			if !privateMixin(d.Name) && strings.TrimSpace(d.Docs) == "" {
+				findings = append(findings, newLintFinding(d.File, d.Line, msgLintUndocumented, d.Name))
+			}
+
+			walk(d.Children)
+		}
+	}
+
+	walk(docs)
+
+	return
+}
+
+// privateMixin reports whether a mixin is private, which it is if its name
+// starts with an underscore.
+func privateMixin(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+/*
+A lintFinding is a single problem reported by a lint rule. The message of a
+built-in rule is identified by its ID, and is always recorded in English so
+that baselines work whatever the language. It's only localised when it's
+printed.
+*/
+type lintFinding struct {
+	Rule    string    `json:"rule"`
+	File    string    `json:"file"`
+	Line    int       `json:"line"`
+	ID      messageID `json:"id,omitempty"`
+	Message string    `json:"message"`
+	args    []interface{}
+}
+
+func newLintFinding(file string, line int, id messageID, args ...interface{}) lintFinding {
+	return lintFinding{
+		File:    file,
+		Line:    line,
+		ID:      id,
+		Message: localiseIn(defaultLanguage, id, args...),
+		args:    args,
+	}
+}
+
+func (f lintFinding) String() string {
+
+	message := f.Message
+
+	if f.ID != "" {
+		message = localise(f.ID, f.args...)
+	}
+
+	// Findings in the rendered output can't be traced back to a line
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", f.File, message, f.Rule)
+	}
+
+	return fmt.Sprintf("%s:%d: %s (%s)", f.File, f.Line, message, f.Rule)
+}
+
+// key identifies a finding in a baseline. Line numbers aren't part of it,
+// so that unrelated edits above a finding don't make it new again.
+func (f lintFinding) key() string {
+	return f.Rule + "\x00" + filepath.ToSlash(f.File) + "\x00" + f.Message
+}
+
+type lintFindings []lintFinding
+
+func (l lintFindings) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+
+		if l[i].File != l[j].File {
+			return l[i].File < l[j].File
+		}
+
+		return l[i].Line < l[j].Line
+	})
+}
+
+// without returns the findings that aren't in the baseline. A finding that
+// appears more often than it does in the baseline is new, so each baseline
+// entry only accounts for one finding.
+func (l lintFindings) without(baseline lintFindings) (remaining lintFindings) {
+
+	known := make(map[string]int, len(baseline))
+
+	for _, f := range baseline {
+		known[f.key()]++
+	}
+
+	for _, f := range l {
+
+		if known[f.key()] > 0 {
+			known[f.key()]--
+			continue
+		}
+
+		remaining = append(remaining, f)
+	}
+
+	return
+}
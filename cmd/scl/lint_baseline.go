@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// loadLintFindings reads a baseline written by lintFindings.write.
+func loadLintFindings(fileName string) (findings lintFindings, err error) {
+
+	content, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &findings); err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+func (l lintFindings) write(fileName string) error {
+
+	if l == nil {
+		l = lintFindings{}
+	}
+
+	content, err := json.MarshalIndent(l, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fileName, append(content, '\n'), 0644)
+}
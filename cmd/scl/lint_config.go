@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+
+	"github.com/homemade/scl"
+)
+
+// lintConfigFileName is the name of the project lint configuration, which is
+// found in the same way as the workspace file.
+const lintConfigFileName = "scl.lint"
+
+/*
+A lintConfig holds the project's lint settings. Built-in rules can be turned
+off, and simple rules can be added without writing any Go:
+
+	disable = ["undocumented-mixin"]
+
+	rule "mixin-names" {
+	  mixin_name = "^[a-z][a-zA-Z0-9]*$"
+	  message    = "Mixin names should be camel case"
+	}
+
+	rule "tagged-resources" {
+	  block   = "^resource aws_"
+	  require = ["tags"]
+	}
+
+	rule "no-latest" {
+	  pattern = ":latest\""
+	}
+
+A mixin_name rule reports mixins whose names don't match. A block rule reports
+blocks in the output whose keys, joined with spaces, match but which lack any
+of the required attributes. A pattern rule reports output lines that match.
+*/
+type lintConfig struct {
+	disabled map[string]bool
+	rules    []lintRule
+}
+
+type lintRuleConfig struct {
+	MixinName string   `hcl:"mixin_name"`
+	Block     string   `hcl:"block"`
+	Require   []string `hcl:"require"`
+	Pattern   string   `hcl:"pattern"`
+	Message   string   `hcl:"message"`
+}
+
+func loadLintConfig(path string) (config lintConfig, err error) {
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return config, err
+	}
+
+	raw := struct {
+		Disable []string                  `hcl:"disable"`
+		Rules   map[string]lintRuleConfig `hcl:"rule"`
+	}{}
+
+	if err := hcl.Decode(&raw, string(content)); err != nil {
+		return config, fmt.Errorf("Can't decode %s: %s", path, err)
+	}
+
+	config.disabled = make(map[string]bool)
+
+	for _, name := range raw.Disable {
+		config.disabled[name] = true
+	}
+
+	// Rules are checked in name order, so findings come out the same way
+	// each time
+	names := make([]string, 0, len(raw.Rules))
+
+	for name := range raw.Rules {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		check, err := raw.Rules[name].check()
+
+		if err != nil {
+			return config, fmt.Errorf("Can't decode %s: rule %s: %s", path, name, err)
+		}
+
+		config.rules = append(config.rules, lintRule{name: name, check: check})
+	}
+
+	return config, nil
+}
+
+// activeRules returns the built-in rules which haven't been disabled, followed by
+// the project's own rules.
+func (c lintConfig) activeRules() (rules []lintRule) {
+
+	for _, rule := range lintRules {
+		if !c.disabled[rule.name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	return append(rules, c.rules...)
+}
+
+func (r lintRuleConfig) check() (func(p scl.Parser, fileName string) (lintFindings, error), error) {
+
+	kinds := 0
+
+	for _, set := range []bool{r.MixinName != "", r.Block != "", r.Pattern != ""} {
+		if set {
+			kinds++
+		}
+	}
+
+	if kinds != 1 {
+		return nil, fmt.Errorf("exactly one of mixin_name, block or pattern is required")
+	}
+
+	switch {
+
+	case r.MixinName != "":
+
+		matcher, err := regexp.Compile(r.MixinName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return r.checkMixinNames(matcher), nil
+
+	case r.Block != "":
+
+		matcher, err := regexp.Compile(r.Block)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(r.Require) == 0 {
+			return nil, fmt.Errorf("a block rule needs at least one required attribute")
+		}
+
+		return r.checkBlocks(matcher), nil
+
+	default:
+
+		matcher, err := regexp.Compile(r.Pattern)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return r.checkPattern(matcher), nil
+	}
+}
+
+func (r lintRuleConfig) message(format string, args ...interface{}) string {
+
+	if r.Message != "" {
+		return fmt.Sprintf("%s: %s", fmt.Sprintf(format, args...), r.Message)
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+func (r lintRuleConfig) checkMixinNames(matcher *regexp.Regexp) func(p scl.Parser, fileName string) (lintFindings, error) {
+
+	return func(p scl.Parser, fileName string) (findings lintFindings, err error) {
+
+		for _, b := range p.Coverage() {
+
+			if b.Kind != scl.CoverageMixin || filepath.Clean(b.File) != filepath.Clean(fileName) {
+				continue
+			}
+
+			if !matcher.MatchString(b.Name) {
+				findings = append(findings, lintFinding{
+					File:    b.File,
+					Line:    b.Line,
+					Message: r.message("Mixin name %s doesn't match %s", b.Name, matcher),
+				})
+			}
+		}
+
+		return
+	}
+}
+
+// checkBlocks reports blocks in the output which are missing required
+// attributes. The output isn't mapped back to the source, so the findings
+// have no line numbers.
+func (r lintRuleConfig) checkBlocks(matcher *regexp.Regexp) func(p scl.Parser, fileName string) (lintFindings, error) {
+
+	return func(p scl.Parser, fileName string) (findings lintFindings, err error) {
+
+		root, err := hcl.Parse(p.String())
+
+		if err != nil {
+			return nil, err
+		}
+
+		var walk func(list *ast.ObjectList, path []string)
+
+		walk = func(list *ast.ObjectList, path []string) {
+
+			for _, item := range list.Items {
+
+				object, ok := item.Val.(*ast.ObjectType)
+
+				if !ok {
+					continue
+				}
+
+				keys := append(append([]string{}, path...), objectKeys(item)...)
+				name := strings.Join(keys, " ")
+
+				if matcher.MatchString(name) {
+					for _, attribute := range r.Require {
+						if object.List.Filter(attribute).Items == nil {
+							findings = append(findings, lintFinding{
+								File:    fileName,
+								Message: r.message("Block %s has no %s attribute", name, attribute),
+							})
+						}
+					}
+				}
+
+				walk(object.List, keys)
+			}
+		}
+
+		if list, ok := root.Node.(*ast.ObjectList); ok {
+			walk(list, nil)
+		}
+
+		return
+	}
+}
+
+func (r lintRuleConfig) checkPattern(matcher *regexp.Regexp) func(p scl.Parser, fileName string) (lintFindings, error) {
+
+	return func(p scl.Parser, fileName string) (findings lintFindings, err error) {
+
+		for _, line := range strings.Split(p.String(), "\n") {
+			if matcher.MatchString(line) {
+				findings = append(findings, lintFinding{
+					File:    fileName,
+					Message: r.message("Output %s matches %s", strings.TrimSpace(line), matcher),
+				})
+			}
+		}
+
+		return
+	}
+}
+
+func objectKeys(item *ast.ObjectItem) (keys []string) {
+
+	for _, k := range item.Keys {
+
+		key := k.Token.Text
+
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+
+		keys = append(keys, key)
+	}
+
+	return
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/homemade/scl"
+)
+
+func Test_OnlyPublicMixinsMustBeDocumented(t *testing.T) {
+
+	for cycle, input := range []struct {
+		source   string
+		expected []string
+	}{
+		{
+			source:   "/*\n  Documented\n*/\n@documented()\n  a = 1",
+			expected: nil,
+		},
+		{
+			source:   "@undocumented()\n  a = 1",
+			expected: []string{"undocumented"},
+		},
+		{
+			source:   "@_private()\n  a = 1",
+			expected: nil,
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p, err := scl.NewParser(scl.NewDiskSystem())
+		require.Nil(t, err)
+		p.AddVirtualFile("lint.scl", []byte(input.source))
+
+		findings, err := lintUndocumentedMixins(p, "lint.scl")
+		require.Nil(t, err)
+
+		var names []string
+
+		for _, f := range findings {
+			names = append(names, f.args[0].(string))
+		}
+
+		require.Equal(t, input.expected, names)
+	}
+}
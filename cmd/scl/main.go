@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Masterminds/vcs"
-	"github.com/aryann/difflib"
 	"github.com/tucnak/climax"
 
 	"github.com/homemade/scl"
@@ -20,11 +21,25 @@ import (
 func main() {
 	app := climax.New("scl")
 	app.Brief = "Scl is a tool for managing SCL soure code."
-	app.Version = "1.3.1"
-
-	app.AddCommand(getCommand(os.Stdout, os.Stderr))
-	app.AddCommand(runCommand(os.Stdout, os.Stderr))
-	app.AddCommand(testCommand(os.Stdout, os.Stderr))
+	app.Version = version
+
+	app.AddCommand(withStats(getCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(runCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(testCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(newLibCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(lintCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(vetCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(docCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(searchCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(depsCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(renameCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(refactorCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(withStats(fixCommand(os.Stdout, os.Stderr)))
+	app.AddCommand(versionCommand(os.Stdout, os.Stderr))
+	app.AddCommand(selfUpdateCommand(os.Stdout, os.Stderr))
+	app.AddCommand(statsCommand(os.Stdout, os.Stderr))
+
+	cleanUpOnSignal()
 
 	os.Exit(app.Run())
 }
@@ -35,42 +50,291 @@ func runCommand(stdout io.Writer, stderr io.Writer) climax.Command {
 		Name:  "run",
 		Brief: "Transform one or more .scl files into HCL",
 		Usage: `[options] <filename.scl...>`,
-		Help:  `Transform one or more .scl files into HCL. Output is written to stdout.`,
-
-		Flags: standardParserParams(),
+		Help:  `Transform one or more .scl files into HCL. Output is written to stdout, or to a file for each input with --output-dir. Each file is parsed by its own parser, so nothing one file declares or breaks is seen by the next. The run stops at the first file that fails, unless --keep-going is given.`,
+
+		Flags: append(append(standardParserParams(), outputFormatParams()...),
+			climax.Flag{
+				Name:  "json",
+				Short: "j",
+				Usage: `--json`,
+				Help:  `Render the output as JSON rather than HCL`,
+			},
+			climax.Flag{
+				Name:  "stamp",
+				Usage: `--stamp`,
+				Help:  `Add the scl version, commit and build date to the comment before each file's output`,
+			},
+			climax.Flag{
+				Name:  "redact",
+				Usage: `--redact`,
+				Help:  `Replace the values of params from encrypted --param-files in the output`,
+			},
+			climax.Flag{
+				Name:  "inputs",
+				Usage: `--inputs`,
+				Help:  `List the environment variables, params and files each file read on stderr`,
+			},
+			climax.Flag{
+				Name:  "hermetic",
+				Usage: `--hermetic`,
+				Help:  `Fail if a file reads an environment variable or file not allowed by --allow-env or --allow-file. The files given on the command line are always allowed`,
+			},
+			climax.Flag{
+				Name:     "allow-env",
+				Usage:    `--allow-env HOME,AWS_*`,
+				Help:     `Comma-separated list of environment variable name patterns that --hermetic allows`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "allow-file",
+				Usage:    `--allow-file "vendor/*/*.scl,lib/*.scl"`,
+				Help:     `Comma-separated list of file path patterns that --hermetic allows`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "watch",
+				Usage: `--watch`,
+				Help:  `Run again whenever one of the files, or anything they include, changes`,
+			},
+			climax.Flag{
+				Name:     "output-dir",
+				Short:    "o",
+				Usage:    `--output-dir /path/to/output`,
+				Help:     `Write each file's output to a .hcl or .json file of the same name in the directory, instead of stdout. Files are replaced atomically, so they're never left partly written`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "keep-going",
+				Short: "k",
+				Usage: `--keep-going`,
+				Help:  `Render every file, even after one fails, and list the files that failed at the end`,
+			},
+		),
 
 		Handle: func(ctx climax.Context) int {
 
 			if len(ctx.Args) == 0 {
-				fmt.Fprintf(stderr, "At least one filename is required. See `sep help run` for syntax")
+				fmt.Fprint(stderr, localise(msgFilenameRequired, "run"))
 				return 1
 			}
 
-			params, includePaths := parserParams(ctx)
+			if ctx.Is("stamp") && ctx.Is("json") {
+				fmt.Fprint(stderr, localise(msgStampWithJSON))
+				return 1
+			}
 
-			for _, fileName := range ctx.Args {
+			params, environment, includePaths, err := parserParams(ctx)
 
-				parser, err := scl.NewParser(scl.NewDiskSystem())
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
 
-				if err != nil {
-					fmt.Fprintf(stderr, "Error: Unable to create new parser in CWD: %s\n", err.Error())
+			redactor := params.redactor()
+			stderr := redactor.writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			format, err := outputFormat(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			output := newSyncOutput(stdout, stderr)
+			allowlist := newInputAllowlist(ctx)
+			outputDir, _ := ctx.Get("output-dir")
+
+			if outputDir != "" {
+				if err := checkOutputFileNames(outputDir, ctx.Args, ctx.Is("json")); err != nil {
+					fmt.Fprint(stderr, localise(msgError, err.Error()))
 					return 1
 				}
+			}
+
+			runFile := func(fileName string, out *fileOutput) {
+
+				// A bug triggered by one file mustn't stop the others
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Fprint(&out.stderr, localise(msgParsePanic, fileName, r))
+						out.failures++
+					}
+				}()
 
-				for _, includeDir := range includePaths {
-					parser.AddIncludePath(includeDir)
+				parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+				if err != nil {
+					fmt.Fprint(&out.stderr, localise(msgCreateParser, err.Error()))
+					out.failures++
+					return
 				}
 
-				for _, p := range params {
-					parser.SetParam(p.name, p.value)
+				parser.SetOutputFormat(format)
+
+				if ctx.Is("redact") {
+					parser.SetPostprocessor(redactor.postprocess)
 				}
 
 				if err := parser.Parse(fileName); err != nil {
-					fmt.Fprintf(stderr, "Error: Unable to parse file: %s\n", err.Error())
+					fmt.Fprint(&out.stderr, localise(msgParseFile, err.Error()))
+					out.failures++
+					return
+				}
+
+				for _, w := range parser.Warnings() {
+					fmt.Fprint(&out.stderr, localise(msgWarning, w))
+				}
+
+				inputs := newInputReport(fileName, parser.Inputs(), parser.Environment(), params)
+
+				if ctx.Is("inputs") {
+					inputs.write(&out.stderr)
+				}
+
+				if ctx.Is("hermetic") {
+					if undeclared := allowlist.undeclared(inputs); len(undeclared) > 0 {
+						fmt.Fprint(&out.stderr, localise(msgUndeclaredInputs, fileName))
+
+						for _, input := range undeclared {
+							fmt.Fprintf(&out.stderr, "\t%s\n", input)
+						}
+
+						out.failures++
+						return
+					}
+				}
+
+				var rendered string
+
+				switch {
+				case ctx.Is("json"):
+
+					encoded, err := renderJSON(parser.String())
+
+					if err != nil {
+						fmt.Fprint(&out.stderr, localise(msgRenderJSON, err.Error()))
+						out.failures++
+						return
+					}
+
+					rendered = string(encoded)
+
+				case ctx.Is("stamp"):
+					rendered = fmt.Sprintf("/* %s, generated by %s */\n%s", fileName, currentBuildInfo(), parser)
+
+				default:
+					rendered = fmt.Sprintf("/* %s */\n%s", fileName, parser)
+				}
+
+				if outputDir == "" {
+
+					fmt.Fprintf(&out.stdout, "%s\n", rendered)
+
+					if !ctx.Is("json") {
+						fmt.Fprintln(&out.stdout)
+					}
+
+					return
+				}
+
+				outputName := outputFileName(outputDir, fileName, ctx.Is("json"))
+
+				if err := os.MkdirAll(filepath.Dir(outputName), 0755); err != nil {
+					fmt.Fprint(&out.stderr, localise(msgWriteFile, err.Error()))
+					out.failures++
+					return
+				}
+
+				if err := writeFileAtomically(outputName, []byte(rendered+"\n"), 0644); err != nil {
+					fmt.Fprint(&out.stderr, localise(msgWriteFile, err.Error()))
+					out.failures++
+					return
+				}
+
+				fmt.Fprint(&out.stdout, localise(msgWrote, outputName))
+			}
+
+			if ctx.Is("watch") {
+
+				watch, err := newFileWatch()
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgWatchFiles, err.Error()))
+					return 1
+				}
+
+				defer watch.stop()
+
+				// Failures don't stop a watch; the next change may fix them
+				for {
+
+					for _, fileName := range ctx.Args {
+
+						out := &fileOutput{}
+						runFile(fileName, out)
+						output.flush(out)
+
+						if parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive); err == nil {
+							if err := watch.add(watchInputs(parser, fileName)...); err != nil {
+								fmt.Fprint(stderr, localise(msgWatchFile, fileName, err.Error()))
+							}
+						}
+					}
+
+					fmt.Fprint(stderr, localise(msgWatching, len(watch.watched)))
+
+					e, ok := watch.wait()
+
+					if !ok {
+						return 0
+					}
+
+					fmt.Fprint(stderr, localise(msgRunningAgain, e.Path, e.Op))
+				}
+			}
+
+			var failed []string
+
+			for _, fileName := range ctx.Args {
+
+				out := &fileOutput{}
+				runFile(fileName, out)
+				output.flush(out)
+
+				if out.failures == 0 {
+					continue
+				}
+
+				if !ctx.Is("keep-going") {
 					return 1
 				}
 
-				fmt.Fprintf(stdout, "/* %s */\n%s\n\n", fileName, parser)
+				failed = append(failed, fileName)
+			}
+
+			if len(failed) > 0 {
+
+				fmt.Fprint(stderr, localise(msgFailedFiles, len(failed), len(ctx.Args)))
+
+				for _, fileName := range failed {
+					fmt.Fprintf(stderr, "\t%s\n", fileName)
+				}
+
+				return 1
 			}
 
 			return 0
@@ -83,8 +347,8 @@ func getCommand(stdout io.Writer, stderr io.Writer) climax.Command {
 	return climax.Command{
 		Name:  "get",
 		Brief: "Download libraries from verion control",
-		Usage: `[options] <url...>`,
-		Help:  "Get downloads the dependencies specified by the URLs provided, cloning or checking them out from their VCS.",
+		Usage: `[options] <url[//subdir][@version]...>`,
+		Help:  "Get downloads the dependencies specified by the URLs provided, cloning or checking them out from their VCS. A //subdir suffix checks out only that directory of a git repository, and an @version suffix checks out a tag, branch or commit.",
 
 		Flags: []climax.Flag{
 			{
@@ -106,12 +370,61 @@ func getCommand(stdout io.Writer, stderr io.Writer) climax.Command {
 				Usage: `--verbose`,
 				Help:  `Print names of repositories as they are acquired or updated`,
 			},
+			{
+				Name:     "mirrors",
+				Short:    "m",
+				Usage:    `--mirrors /path/to/mirrors.txt`,
+				Help:     `A file mapping URL prefixes to mirrors, one "prefix replacement" pair per line`,
+				Variable: true,
+			},
+			{
+				Name:     "proxy",
+				Usage:    `--proxy http://proxy.example.com:3128`,
+				Help:     `An HTTP(S) proxy to fetch through. Defaults to the HTTP_PROXY and HTTPS_PROXY environment variables`,
+				Variable: true,
+			},
+			{
+				Name:  "progress",
+				Short: "P",
+				Usage: `--progress`,
+				Help:  `Show the status of each repository, and how long it's taking, as it's fetched or updated`,
+			},
+			{
+				Name:     "retries",
+				Short:    "r",
+				Usage:    `--retries 3`,
+				Help:     `The number of times to retry a failed fetch or update, waiting twice as long before each retry. Default is 0`,
+				Variable: true,
+			},
+			{
+				Name:     "timeout",
+				Short:    "t",
+				Usage:    `--timeout 5m`,
+				Help:     `The maximum time for each attempt to fetch or update a repository. An attempt that takes longer is stopped, and retried if --retries allows. Default is no limit`,
+				Variable: true,
+			},
+			{
+				Name:  "verify",
+				Usage: `--verify`,
+				Help:  `Parse every .scl file in each new or updated repository, and fail if any of them can't be parsed`,
+			},
+			{
+				Name:  "check",
+				Short: "c",
+				Usage: `--check`,
+				Help:  `Report newer upstream tags for existing repositories without changing anything`,
+			},
+			{
+				Name:  "compatible",
+				Usage: `--compatible`,
+				Help:  `With --check, only report tags with the same major version as the current one`,
+			},
 		},
 
 		Handle: func(ctx climax.Context) int {
 
 			if len(ctx.Args) == 0 {
-				fmt.Fprintf(stderr, "At least one dependency is required. See `sep help get` for syntax")
+				fmt.Fprint(stderr, localise(msgDependencyRequired))
 				return 1
 			}
 
@@ -124,170 +437,154 @@ func getCommand(stdout io.Writer, stderr io.Writer) climax.Command {
 			vendorDir, err := filepath.Abs(vendorDir)
 
 			if err != nil {
-				fmt.Fprintln(stderr, "Can't get path:", err.Error())
+				fmt.Fprint(stderr, localise(msgGetPath, err.Error()))
 				return 1
 			}
 
-			newCount, updatedCount := 0, 0
-
-			for _, dep := range ctx.Args {
+			var mirrors mirrorMap
 
-				remote := fmt.Sprintf("https://%s", strings.TrimPrefix(dep, "https://"))
-				path := filepath.Join(vendorDir, dep)
-
-				if err := os.MkdirAll(path, os.ModeDir); err != nil {
-					fmt.Fprintf(stderr, "Can't create path %s: %s\n", vendorDir, err.Error())
+			if mirrorsPath, set := ctx.Get("mirrors"); set {
+				if mirrors, err = loadMirrors(mirrorsPath); err != nil {
+					fmt.Fprint(stderr, localise(msgReadMirrors, err.Error()))
 					return 1
 				}
+			}
 
-				repo, err := vcs.NewRepo(remote, path)
-
-				if err != nil {
-					fmt.Fprintf(stderr, "[%s] Can't create repo: %s", dep, err.Error())
-					continue
-				}
-
-				if repo.CheckLocal() {
-
-					if !ctx.Is("update") {
-						if ctx.Is("verbose") {
-							fmt.Fprintf(stderr, "[%s] already present, run with -u to update\n", dep)
-						}
-						continue
-					}
-
-					if err := repo.Update(); err != nil {
-						fmt.Fprintf(stderr, "[%s] Can't update repo: %s\n", dep, err.Error())
-						continue
-					}
-
-					updatedCount++
-
-					if ctx.Is("verbose") {
-						fmt.Fprintf(stdout, "%s updated successfully\n", dep)
-					}
-
-				} else {
-					if err := repo.Get(); err != nil {
-						fmt.Fprintf(stderr, "[%s] Can't fetch repo: %s\n", dep, err.Error())
-						continue
-					}
+			// The VCS tools are run as child processes, so they pick the proxy up
+			// from the environment
+			if proxy, set := ctx.Get("proxy"); set {
+				os.Setenv("HTTP_PROXY", proxy)
+				os.Setenv("HTTPS_PROXY", proxy)
+			}
 
-					newCount++
+			policy := retryPolicy{backoff: time.Second}
 
-					if ctx.Is("verbose") {
-						fmt.Fprintf(stdout, "%s fetched successfully.\n", dep)
-					}
+			if retries, set := ctx.Get("retries"); set {
+				if policy.retries, err = strconv.Atoi(retries); err != nil || policy.retries < 0 {
+					fmt.Fprint(stderr, localise(msgInvalidRetries, retries))
+					return 1
 				}
 			}
 
-			if ctx.Is("verbose") {
-				fmt.Fprintf(stdout, "\nDone. %d dependencie(s) created, %d dependencie(s) updated.\n", newCount, updatedCount)
+			if timeout, set := ctx.Get("timeout"); set {
+				if policy.timeout, err = time.ParseDuration(timeout); err != nil {
+					fmt.Fprint(stderr, localise(msgInvalidTimeout, err.Error()))
+					return 1
+				}
 			}
 
-			return 0
-		},
-	}
-}
-
-func testCommand(stdout io.Writer, stderr io.Writer) climax.Command {
-
-	return climax.Command{
-		Name:  "test",
-		Brief: "Parse each .scl file in a directory and compare the output to an .hcl file",
-		Usage: `[options] [file-glob...]`,
-		Help:  "Parse each .scl file in a directory and compare the output to an .hcl file",
-
-		Flags: standardParserParams(),
-
-		Handle: func(ctx climax.Context) int {
+			if ctx.Is("check") {
+				return checkDependencies(vendorDir, mirrors, ctx.Args, ctx.Is("compatible"), ctx.Is("verbose"), stdout, stderr)
+			}
 
-			errors := 0
+			newCount, updatedCount, brokenCount := 0, 0, 0
+			status := newProgress(stderr, len(ctx.Args))
 
-			reportError := func(path string, err string, args ...interface{}) {
-				fmt.Fprintf(stderr, "%-7s %s %s\n", "FAIL", path, fmt.Sprintf(err, args...))
-				errors++
-			}
+			verify := func(dep dependency, repo vcs.Repo) {
 
-			if len(ctx.Args) == 0 {
-				fmt.Fprintf(stderr, "At least one file glob is required. See `sep help test` for syntax")
-				return 1
-			}
+				if !ctx.Is("verify") {
+					return
+				}
 
-			newlineMatcher := regexp.MustCompile("\n\n")
-			params, includePaths := parserParams(ctx)
+				root := repo.LocalPath()
 
-			for _, fileName := range ctx.Args {
+				if dep.subdir != "" {
+					root = filepath.Join(root, dep.subdir)
+				}
 
-				fs := scl.NewDiskSystem()
-				parser, err := scl.NewParser(fs)
-				now := time.Now()
+				failures, err := verifyDependency(root, vendorDir)
 
 				if err != nil {
-					reportError("Unable to create new parser in CWD: %s", err.Error())
-					continue
+					failures = append(failures, err)
 				}
 
-				for _, includeDir := range includePaths {
-					parser.AddIncludePath(includeDir)
+				for _, failure := range failures {
+					fmt.Fprint(stderr, localise(msgVerificationFailed, dep, failure.Error()))
 				}
 
-				for _, p := range params {
-					parser.SetParam(p.name, p.value)
+				if len(failures) > 0 {
+					brokenCount++
 				}
+			}
 
-				if err := parser.Parse(fileName); err != nil {
-					reportError(fileName, "Unable to parse file: %s", err.Error())
-					continue
-				}
+			for i, arg := range ctx.Args {
 
-				hclFilePath := strings.TrimSuffix(fileName, ".scl") + ".hcl"
-				hclFile, _, err := fs.ReadCloser(hclFilePath)
+				dep := parseDependency(arg)
+				repo, err := dependencyRepo(vendorDir, mirrors, dep.name)
 
 				if err != nil {
-					fmt.Fprintf(stdout, "%-7s %s [no .hcl file]\n", "?", fileName)
+					fmt.Fprint(stderr, localise(msgCreateRepo, dep, err.Error()))
 					continue
 				}
 
-				hcl, err := ioutil.ReadAll(hclFile)
+				if err := os.MkdirAll(filepath.Dir(repo.LocalPath()), 0755); err != nil {
+					fmt.Fprint(stderr, localise(msgCreatePath, vendorDir, err.Error()))
+					return 1
+				}
 
-				if err != nil {
-					reportError(fileName, "Unable to read .hcl file: %s", err.Error())
-					continue
+				retry := func(attempt int, err error) {
+					fmt.Fprint(stderr, localise(msgRetrying, dep, attempt, err.Error()))
 				}
 
-				hclLines := strings.Split(strings.TrimSuffix(newlineMatcher.ReplaceAllString(string(hcl), "\n"), "\n"), "\n")
-				sclLines := strings.Split(parser.String(), "\n")
+				if repo.CheckLocal() {
 
-				diff := difflib.Diff(hclLines, sclLines)
+					if !ctx.Is("update") {
+						if ctx.Is("verbose") {
+							fmt.Fprint(stderr, localise(msgAlreadyPresent, dep))
+						}
+						continue
+					}
 
-				success := true
+					finish := func(string) {}
 
-				for _, d := range diff {
-					if d.Delta != difflib.Common {
-						success = false
+					if ctx.Is("progress") {
+						finish = status.start(i, dep.String(), localise(msgProgressUpdating))
 					}
-				}
 
-				if !success {
-					reportError(fileName, "Diff failed:")
+					if err := policy.do(func(ctx context.Context) error { return dep.update(ctx, repo) }, retry); err != nil {
+						finish(localise(msgProgressFailed))
+						fmt.Fprint(stderr, localise(msgUpdateRepo, dep, err.Error()))
+						continue
+					}
 
-					fmt.Fprintln(stderr)
+					finish(localise(msgProgressUpdated))
+					updatedCount++
+					verify(dep, repo)
 
-					for _, d := range diff {
-						fmt.Fprintf(stderr, "\t%s\n", d.String())
+					if ctx.Is("verbose") {
+						fmt.Fprint(stdout, localise(msgDependencyUpdated, dep))
 					}
 
-					fmt.Fprintln(stderr)
+				} else {
 
-					continue
+					finish := func(string) {}
+
+					if ctx.Is("progress") {
+						finish = status.start(i, dep.String(), localise(msgProgressFetching))
+					}
+
+					if err := policy.do(func(ctx context.Context) error { return dep.get(ctx, repo) }, retry); err != nil {
+						finish(localise(msgProgressFailed))
+						fmt.Fprint(stderr, localise(msgFetchRepo, dep, err.Error()))
+						continue
+					}
+
+					finish(localise(msgProgressFetched))
+					newCount++
+					verify(dep, repo)
+
+					if ctx.Is("verbose") {
+						fmt.Fprint(stdout, localise(msgDependencyFetched, dep))
+					}
 				}
+			}
 
-				fmt.Fprintf(stdout, "%-7s %s\t%.3fs\n", "ok", fileName, time.Since(now).Seconds())
+			if ctx.Is("verbose") {
+				fmt.Fprint(stdout, localise(msgGetDone, newCount, updatedCount))
 			}
 
-			if errors > 0 {
-				fmt.Fprintf(stderr, "\n[FAIL] %d error(s)\n", errors)
+			if brokenCount > 0 {
+				fmt.Fprint(stderr, localise(msgFailedVerification, brokenCount))
 				return 1
 			}
 
@@ -296,6 +593,27 @@ func testCommand(stdout io.Writer, stderr io.Writer) climax.Command {
 	}
 }
 
+func dependencyRepo(vendorDir string, mirrors mirrorMap, name string) (vcs.Repo, error) {
+
+	path := filepath.Join(vendorDir, name)
+
+	// Mirrors take precedence over everything else, since the upstream
+	// host may not be reachable at all
+	if mirrored := mirrors.rewrite(name); mirrored != name {
+		return vcs.NewRepo("https://"+mirrored, path)
+	}
+
+	// Unknown hosts may be vanity URLs pointing at the real repository. If
+	// the lookup fails, fall back to treating the URL as a repository.
+	if !isKnownHost(name) {
+		if v, err := resolveVanity(name); err == nil && v != nil {
+			return v.repo(filepath.Join(vendorDir, v.prefix))
+		}
+	}
+
+	return vcs.NewRepo("https://"+name, path)
+}
+
 func standardParserParams() []climax.Flag {
 
 	return []climax.Flag{
@@ -313,21 +631,116 @@ func standardParserParams() []climax.Flag {
 			Help:     `Comma-separated list of include paths`,
 			Variable: true,
 		},
+		{
+			Name:     "param-file",
+			Short:    "pf",
+			Usage:    `--param-file params.json,secrets.enc.json`,
+			Help:     `Comma-separated list of JSON files of params, which may be encrypted with SOPS or age. Values from encrypted files are redacted from error messages`,
+			Variable: true,
+		},
 		{
 			Name:  "no-env",
 			Short: "ne",
 			Usage: `--no-env`,
 			Help:  `Don't import envionment variables when parsing the SCL`,
 		},
+		{
+			Name:     "workspace",
+			Short:    "w",
+			Usage:    `--workspace /path/to/scl.work`,
+			Help:     `A workspace file mapping library names to local directories. Default is the nearest scl.work file`,
+			Variable: true,
+		},
+		{
+			Name:  "no-workspace",
+			Short: "nw",
+			Usage: `--no-workspace`,
+			Help:  `Don't look for a workspace file`,
+		},
+		{
+			Name:     "case-insensitive-includes",
+			Usage:    `--case-insensitive-includes true`,
+			Help:     `Whether includes ignore case, warning about files whose names differ only by case. Default is true on macOS and Windows, and false elsewhere`,
+			Variable: true,
+		},
+	}
+
+}
+
+// Includes ignore case on platforms whose filesystems usually do too, so that
+// names which differ only by case are warned about rather than picked at random
+var caseInsensitiveIncludes = runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+
+// includesIgnoreCase reads --case-insensitive-includes, defaulting to whether
+// the platform's filesystems usually ignore case.
+func includesIgnoreCase(ctx climax.Context) (bool, error) {
+
+	value, set := ctx.Get("case-insensitive-includes")
+
+	if !set {
+		return caseInsensitiveIncludes, nil
+	}
+
+	ignoreCase, err := strconv.ParseBool(value)
+
+	if err != nil {
+		return false, errors.New(localise(msgInvalidCaseInsensitive, value))
+	}
+
+	return ignoreCase, nil
+}
+
+// configuredParser creates a parser for files on disk with the given params,
+// environment, include paths and workspace libraries. Params replace
+// environment variables with the same name. Files encrypted with SOPS are
+// decrypted as they're read.
+func configuredParser(params paramSlice, environment scl.Environment, includePaths []string, workspace scl.Workspace, caseInsensitive bool) (scl.Parser, error) {
+
+	parser, err := scl.NewParserFromConfig(scl.Config{
+		FileSystem:      scl.NewDiskSystem(),
+		Environment:     environment,
+		CaseInsensitive: caseInsensitive,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, includeDir := range includePaths {
+		parser.AddIncludePath(includeDir)
+	}
+
+	for name, path := range workspace {
+		parser.AddWorkspaceLibrary(name, path)
+	}
+
+	for _, p := range params {
+		parser.SetParam(p.name, p.value)
 	}
 
+	parser.SetPreprocessor(decryptSOPS)
+
+	return parser, nil
 }
 
-func parserParams(ctx climax.Context) (params paramSlice, includePaths []string) {
+func parserParams(ctx climax.Context) (params paramSlice, environment scl.Environment, includePaths []string, err error) {
 
+	// The environment is snapshotted once, so that every file parsed by a
+	// command sees the same variables
 	if !ctx.Is("no-env") {
-		for _, envVar := range os.Environ() {
-			params.Set(envVar)
+		environment = scl.SnapshotEnvironment()
+	}
+
+	if ps, set := ctx.Get("param-file"); set {
+		for _, path := range strings.Split(ps, ",") {
+
+			fileParams, err := loadParamFile(path)
+
+			if err != nil {
+				return nil, environment, nil, err
+			}
+
+			params = append(params, fileParams...)
 		}
 	}
 
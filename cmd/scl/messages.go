@@ -0,0 +1,597 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+Messages are written in the language named by SCL_LANG, which can be a
+language code such as "de" or a locale such as "de_DE.UTF-8". Messages that
+haven't been translated, and all messages if SCL_LANG is unset or names a
+language with no translations, are written in English. The details of errors
+from the parser and the packages it uses are always in English, as is output
+meant to be read by other tools, such as test result lines, diffs, stats tables
+and listings.
+
+Each message is identified by a messageID. IDs are written in machine-readable
+output, such as lint baselines, so they must never change once released, even
+if the English text does.
+*/
+const languageVariable = "SCL_LANG"
+
+const defaultLanguage = "en"
+
+type messageID string
+
+const (
+	msgLoadParams       messageID = "error.load-params"
+	msgLoadWorkspace    messageID = "error.load-workspace"
+	msgCreateParser     messageID = "error.create-parser"
+	msgReadFile         messageID = "error.read-file"
+	msgWriteFile        messageID = "error.write-file"
+	msgListFiles        messageID = "error.list-files"
+	msgIndexLibraries   messageID = "error.index-libraries"
+	msgWarning          messageID = "warning"
+	msgFilenameRequired messageID = "usage.filename-required"
+	msgFailedErrors     messageID = "summary.failed-errors"
+	msgFailedFindings   messageID = "summary.failed-findings"
+	msgFailedFiles      messageID = "summary.failed-files"
+	msgLintUnusedMixin  messageID = "lint.unused-mixin"
+	msgLintUndocumented messageID = "lint.undocumented-mixin"
+
+	msgError                  messageID = "error"
+	msgParseFile              messageID = "error.parse-file"
+	msgParsePanic             messageID = "error.parse-panic"
+	msgRenderJSON             messageID = "error.render-json"
+	msgUndeclaredInputs       messageID = "error.undeclared-inputs"
+	msgWatchFiles             messageID = "error.watch-files"
+	msgWatchFile              messageID = "error.watch-file"
+	msgListIncludes           messageID = "error.list-includes"
+	msgWriteSite              messageID = "error.write-site"
+	msgReadMetadata           messageID = "error.read-metadata"
+	msgReadDocumentation      messageID = "error.read-documentation"
+	msgFixFile                messageID = "error.fix-file"
+	msgLoadLintConfig         messageID = "error.load-lint-config"
+	msgLintFile               messageID = "error.lint-file"
+	msgWriteBaseline          messageID = "error.write-baseline"
+	msgReadBaseline           messageID = "error.read-baseline"
+	msgRefactor               messageID = "error.refactor"
+	msgRename                 messageID = "error.rename"
+	msgCheckReleases          messageID = "error.check-releases"
+	msgInvalidRelease         messageID = "error.invalid-release"
+	msgFindBinary             messageID = "error.find-binary"
+	msgUpdateSCL              messageID = "error.update-scl"
+	msgReadStats              messageID = "error.read-stats"
+	msgFindOPA                messageID = "error.find-opa"
+	msgListChangedFiles       messageID = "error.list-changed-files"
+	msgListCoverageStatements messageID = "error.list-coverage-statements"
+	msgWriteCoverage          messageID = "error.write-coverage"
+	msgGetPath                messageID = "error.get-path"
+	msgReadMirrors            messageID = "error.read-mirrors"
+	msgCreatePath             messageID = "error.create-path"
+	msgCreateFile             messageID = "error.create-file"
+	msgFileExists             messageID = "error.file-exists"
+	msgCreateRepo             messageID = "error.create-repo"
+	msgCheckDependency        messageID = "error.check-dependency"
+	msgUpdateRepo             messageID = "error.update-repo"
+	msgFetchRepo              messageID = "error.fetch-repo"
+	msgVerificationFailed     messageID = "error.verification-failed"
+	msgFailCreateParser       messageID = "fail.create-parser"
+	msgFailParseFile          messageID = "fail.parse-file"
+	msgFailRenderJSON         messageID = "fail.render-json"
+	msgFailReadFile           messageID = "fail.read-file"
+	msgFailReadExpected       messageID = "fail.read-expected"
+	msgFailCompare            messageID = "fail.compare"
+	msgFailDiff               messageID = "fail.diff"
+	msgFailMutation           messageID = "fail.mutation"
+	msgFailEvaluatePolicies   messageID = "fail.evaluate-policies"
+	msgFailDenied             messageID = "fail.denied"
+	msgSkipNoExpectation      messageID = "skip.no-expectation"
+	msgStampWithJSON          messageID = "usage.stamp-with-json"
+	msgBaselineRequired       messageID = "usage.baseline-required"
+	msgFixRequired            messageID = "usage.fix-required"
+	msgFromToRequired         messageID = "usage.from-to-required"
+	msgUnknownFix             messageID = "usage.unknown-fix"
+	msgRefactoringRequired    messageID = "usage.refactoring-required"
+	msgUnknownRefactoring     messageID = "usage.unknown-refactoring"
+	msgRenameNamesRequired    messageID = "usage.rename-names-required"
+	msgSearchTermRequired     messageID = "usage.search-term-required"
+	msgPolicyRequired         messageID = "usage.policy-required"
+	msgGlobRequired           messageID = "usage.glob-required"
+	msgDependencyRequired     messageID = "usage.dependency-required"
+	msgLibraryNameRequired    messageID = "usage.library-name-required"
+	msgInvalidLibraryName     messageID = "usage.invalid-library-name"
+	msgInvalidRunExpression   messageID = "usage.invalid-run-expression"
+	msgInvalidFailures        messageID = "usage.invalid-failures"
+	msgInvalidRetries         messageID = "usage.invalid-retries"
+	msgInvalidTests           messageID = "usage.invalid-tests"
+	msgInvalidTimeout         messageID = "usage.invalid-timeout"
+	msgInvalidDuration        messageID = "usage.invalid-duration"
+	msgInvalidCaseInsensitive messageID = "usage.invalid-case-insensitive"
+	msgWrote                  messageID = "status.wrote"
+	msgWatching               messageID = "status.watching"
+	msgRunningAgain           messageID = "status.running-again"
+	msgStopped                messageID = "status.stopped"
+	msgFixed                  messageID = "status.fixed"
+	msgDocumented             messageID = "status.documented"
+	msgRecordedFindings       messageID = "status.recorded-findings"
+	msgExtracted              messageID = "status.extracted"
+	msgInlined                messageID = "status.inlined"
+	msgRenamed                messageID = "status.renamed"
+	msgNoReferences           messageID = "status.no-references"
+	msgNoMatchingMixins       messageID = "status.no-matching-mixins"
+	msgSCLUpToDate            messageID = "status.scl-up-to-date"
+	msgSCLAvailable           messageID = "status.scl-available"
+	msgSCLUpdated             messageID = "status.scl-updated"
+	msgStatsDisabled          messageID = "status.stats-disabled"
+	msgNoStats                messageID = "status.no-stats"
+	msgNoCommandsRecorded     messageID = "status.no-commands-recorded"
+	msgInputsReadBy           messageID = "status.inputs-read-by"
+	msgNoInputs               messageID = "status.no-inputs"
+	msgDependencyUpToDate     messageID = "status.dependency-up-to-date"
+	msgDependencyUpdate       messageID = "status.dependency-update"
+	msgNotPresent             messageID = "status.not-present"
+	msgAlreadyPresent         messageID = "status.already-present"
+	msgRetrying               messageID = "status.retrying"
+	msgDependencyUpdated      messageID = "status.dependency-updated"
+	msgDependencyFetched      messageID = "status.dependency-fetched"
+	msgProgressUpdating       messageID = "progress.updating"
+	msgProgressFetching       messageID = "progress.fetching"
+	msgProgressUpdated        messageID = "progress.updated"
+	msgProgressFetched        messageID = "progress.fetched"
+	msgProgressFailed         messageID = "progress.failed"
+	msgDependents             messageID = "summary.dependents"
+	msgIgnoredFindings        messageID = "summary.ignored-findings"
+	msgUpdatesAvailable       messageID = "summary.updates-available"
+	msgGetDone                messageID = "summary.get-done"
+	msgFailedVerification     messageID = "summary.failed-verification"
+	msgStoppedAfter           messageID = "summary.stopped-after"
+	msgNotRunUnchanged        messageID = "summary.not-run-unchanged"
+	msgFlaky                  messageID = "summary.flaky"
+	msgSlowestTests           messageID = "summary.slowest-tests"
+	msgTimePerDirectory       messageID = "summary.time-per-directory"
+	msgCoverageNone           messageID = "summary.coverage-none"
+	msgCoverageFile           messageID = "summary.coverage-file"
+	msgCoverageTotal          messageID = "summary.coverage-total"
+	msgNotCovered             messageID = "summary.not-covered"
+)
+
+// messageCatalogue holds the messages in each language, keyed by ID. Every
+// message must have an English version, which is used when there's no
+// translation.
+var messageCatalogue = map[string]map[messageID]string{
+	"en": {
+		msgLoadParams:       "Error: Unable to load params: %s\n",
+		msgLoadWorkspace:    "Error: Unable to load workspace: %s\n",
+		msgCreateParser:     "Error: Unable to create new parser in CWD: %s\n",
+		msgReadFile:         "Error: Unable to read file: %s\n",
+		msgWriteFile:        "Error: Unable to write file: %s\n",
+		msgListFiles:        "Error: Unable to list files: %s\n",
+		msgIndexLibraries:   "Error: Unable to index libraries: %s\n",
+		msgWarning:          "Warning: %s\n",
+		msgFilenameRequired: "At least one filename is required. See `scl help %s` for syntax",
+		msgFailedErrors:     "\n[FAIL] %d error(s)\n",
+		msgFailedFindings:   "\n[FAIL] %d finding(s)\n",
+		msgFailedFiles:      "\n[FAIL] %d of %d file(s) failed:\n",
+		msgLintUnusedMixin:  "Private mixin %s is never called",
+		msgLintUndocumented: "Mixin %s has no documentation",
+
+		msgError:                  "Error: %s\n",
+		msgParseFile:              "Error: Unable to parse file: %s\n",
+		msgParsePanic:             "Error: Unable to parse file: %s: %v\n",
+		msgRenderJSON:             "Error: Unable to render JSON: %s\n",
+		msgUndeclaredInputs:       "Error: %s read inputs that aren't allowed:\n",
+		msgWatchFiles:             "Error: Unable to watch files: %s\n",
+		msgWatchFile:              "Error: Unable to watch %s: %s\n",
+		msgListIncludes:           "Error: Unable to list includes: %s\n",
+		msgWriteSite:              "Error: Unable to write site: %s\n",
+		msgReadMetadata:           "Error: Unable to read metadata: %s\n",
+		msgReadDocumentation:      "Error: Unable to read documentation: %s\n",
+		msgFixFile:                "Error: Unable to fix %s: %s\n",
+		msgLoadLintConfig:         "Error: Unable to load lint configuration: %s\n",
+		msgLintFile:               "Error: Unable to lint %s: %s\n",
+		msgWriteBaseline:          "Error: Unable to write baseline: %s\n",
+		msgReadBaseline:           "Error: Unable to read baseline: %s\n",
+		msgRefactor:               "Error: Unable to refactor: %s\n",
+		msgRename:                 "Error: Unable to rename: %s\n",
+		msgCheckReleases:          "Error: Unable to check for releases: %s\n",
+		msgInvalidRelease:         "Error: The latest release has an invalid version: %s\n",
+		msgFindBinary:             "Error: Unable to find the scl binary: %s\n",
+		msgUpdateSCL:              "Error: Unable to update scl: %s\n",
+		msgReadStats:              "Error: Unable to read stats: %s\n",
+		msgFindOPA:                "Error: Unable to find the opa binary: %s\n",
+		msgListChangedFiles:       "Unable to list changed files: %s\n",
+		msgListCoverageStatements: "Unable to list library statements for coverage: %s\n",
+		msgWriteCoverage:          "Unable to write coverage report: %s\n",
+		msgGetPath:                "Can't get path: %s\n",
+		msgReadMirrors:            "Can't read mirrors: %s\n",
+		msgCreatePath:             "Can't create path %s: %s\n",
+		msgCreateFile:             "Can't create %s: %s\n",
+		msgFileExists:             "Can't create %s: file already exists\n",
+		msgCreateRepo:             "[%s] Can't create repo: %s\n",
+		msgCheckDependency:        "[%s] Can't check for updates: %s\n",
+		msgUpdateRepo:             "[%s] Can't update repo: %s\n",
+		msgFetchRepo:              "[%s] Can't fetch repo: %s\n",
+		msgVerificationFailed:     "[%s] Verification failed: %s\n",
+		msgFailCreateParser:       "Unable to create new parser in CWD: %s",
+		msgFailParseFile:          "Unable to parse file: %s",
+		msgFailRenderJSON:         "Unable to render JSON: %s",
+		msgFailReadFile:           "Unable to read file: %s",
+		msgFailReadExpected:       "Unable to read %s file: %s",
+		msgFailCompare:            "Unable to compare output with %s file: %s",
+		msgFailDiff:               "Diff failed (%s):",
+		msgFailMutation:           "Output still matches when perturbing: %s",
+		msgFailEvaluatePolicies:   "Unable to evaluate policies: %s",
+		msgFailDenied:             "Denied by policy:",
+		msgSkipNoExpectation:      "no .hcl or .json file",
+		msgStampWithJSON:          "--stamp can't be used with --json, which has no comments\n",
+		msgBaselineRequired:       "--write-baseline requires a --baseline file\n",
+		msgFixRequired:            "A fix is required. See `scl help fix` for syntax",
+		msgFromToRequired:         "Both --from and --to are required. See `scl help fix` for syntax",
+		msgUnknownFix:             "Unknown fix %s. See `scl help fix` for syntax",
+		msgRefactoringRequired:    "A refactoring is required. See `scl help refactor` for syntax",
+		msgUnknownRefactoring:     "Unknown refactoring %s. See `scl help refactor` for syntax",
+		msgRenameNamesRequired:    "The old and new mixin names are required. See `scl help rename` for syntax",
+		msgSearchTermRequired:     "A search term is required. See `scl help search` for syntax",
+		msgPolicyRequired:         "At least one --policy is required. See `scl help vet` for syntax",
+		msgGlobRequired:           "At least one file glob is required. See `scl help test` for syntax",
+		msgDependencyRequired:     "At least one dependency is required. See `scl help get` for syntax",
+		msgLibraryNameRequired:    "Exactly one library name is required. See `scl help new-lib` for syntax\n",
+		msgInvalidLibraryName:     "Invalid library name: %s\n",
+		msgInvalidRunExpression:   "Invalid --run expression: %s\n",
+		msgInvalidFailures:        "Invalid number of failures: %s\n",
+		msgInvalidRetries:         "Invalid number of retries: %s\n",
+		msgInvalidTests:           "Invalid number of tests: %s\n",
+		msgInvalidTimeout:         "Invalid timeout: %s\n",
+		msgInvalidDuration:        "Invalid duration: %s\n",
+		msgInvalidCaseInsensitive: "Invalid --case-insensitive-includes value: %s",
+		msgWrote:                  "Wrote %s\n",
+		msgWatching:               "Watching %d file(s) for changes\n",
+		msgRunningAgain:           "\n%s: %s, running again\n\n",
+		msgStopped:                "\nStopped: %s\n",
+		msgFixed:                  "Fixed %s\n",
+		msgDocumented:             "Documented %d libraries in %s\n",
+		msgRecordedFindings:       "Recorded %d finding(s) in %s\n",
+		msgExtracted:              "%s: extracted %s and replaced %d block(s)\n",
+		msgInlined:                "%s: inlined %d call(s)\n",
+		msgRenamed:                "%s: %d reference(s)\n",
+		msgNoReferences:           "No references to %s found\n",
+		msgNoMatchingMixins:       "No mixins match %q\n",
+		msgSCLUpToDate:            "scl %s is up to date\n",
+		msgSCLAvailable:           "scl %s is available (installed: %s)\n",
+		msgSCLUpdated:             "Updated scl from %s to %s\n",
+		msgStatsDisabled:          "Stats aren't being recorded. Set %s=1 to record them in ~/.scl/stats.jsonl, or %s to choose the file\n",
+		msgNoStats:                "No stats recorded yet in %s\n",
+		msgNoCommandsRecorded:     "No commands recorded in this period\n",
+		msgInputsReadBy:           "Inputs read by %s:\n",
+		msgNoInputs:               "\t(none)\n",
+		msgDependencyUpToDate:     "%s %s is up to date\n",
+		msgDependencyUpdate:       "%s %s -> %s\n",
+		msgNotPresent:             "[%s] not present, run without --check to fetch it\n",
+		msgAlreadyPresent:         "[%s] already present, run with -u to update\n",
+		msgRetrying:               "[%s] Attempt %d failed, retrying: %s\n",
+		msgDependencyUpdated:      "%s updated successfully\n",
+		msgDependencyFetched:      "%s fetched successfully.\n",
+		msgProgressUpdating:       "updating",
+		msgProgressFetching:       "fetching",
+		msgProgressUpdated:        "updated",
+		msgProgressFetched:        "fetched",
+		msgProgressFailed:         "failed",
+		msgDependents:             "\n%d file(s) include %s\n",
+		msgIgnoredFindings:        "\n%d existing finding(s) in %s ignored\n",
+		msgUpdatesAvailable:       "\nDone. %d update(s) available.\n",
+		msgGetDone:                "\nDone. %d dependencie(s) created, %d dependencie(s) updated.\n",
+		msgFailedVerification:     "\n[FAIL] %d dependencie(s) failed verification\n",
+		msgStoppedAfter:           "\nStopped after %d failure(s); %d file(s) not run\n",
+		msgNotRunUnchanged:        "\n%d test(s) not run: unchanged since %s\n",
+		msgFlaky:                  "\n%d test(s) only passed on a retry:\n",
+		msgSlowestTests:           "\nSlowest %d test(s):\n",
+		msgTimePerDirectory:       "\nTime per directory:\n",
+		msgCoverageNone:           "\ncoverage: no mixins or includes used outside the test files\n",
+		msgCoverageFile:           "coverage: %5.1f%% of %d statement(s) in %s\n",
+		msgCoverageTotal:          "coverage: %5.1f%% of %d statement(s) in total\n",
+		msgNotCovered:             "\nNot covered:\n",
+	},
+	"de": {
+		msgLoadParams:       "Fehler: Parameter konnten nicht geladen werden: %s\n",
+		msgLoadWorkspace:    "Fehler: Arbeitsbereich konnte nicht geladen werden: %s\n",
+		msgCreateParser:     "Fehler: Parser im aktuellen Verzeichnis konnte nicht erstellt werden: %s\n",
+		msgReadFile:         "Fehler: Datei konnte nicht gelesen werden: %s\n",
+		msgWriteFile:        "Fehler: Datei konnte nicht geschrieben werden: %s\n",
+		msgListFiles:        "Fehler: Dateien konnten nicht aufgelistet werden: %s\n",
+		msgIndexLibraries:   "Fehler: Bibliotheken konnten nicht indiziert werden: %s\n",
+		msgWarning:          "Warnung: %s\n",
+		msgFilenameRequired: "Mindestens ein Dateiname ist erforderlich. Siehe `scl help %s` für die Syntax",
+		msgFailedErrors:     "\n[FAIL] %d Fehler\n",
+		msgFailedFindings:   "\n[FAIL] %d Befund(e)\n",
+		msgFailedFiles:      "\n[FAIL] %d von %d Datei(en) fehlgeschlagen:\n",
+		msgLintUnusedMixin:  "Privates Mixin %s wird nie aufgerufen",
+		msgLintUndocumented: "Mixin %s ist nicht dokumentiert",
+
+		msgError:                  "Fehler: %s\n",
+		msgParseFile:              "Fehler: Datei konnte nicht geparst werden: %s\n",
+		msgParsePanic:             "Fehler: Datei konnte nicht geparst werden: %s: %v\n",
+		msgRenderJSON:             "Fehler: JSON konnte nicht erzeugt werden: %s\n",
+		msgUndeclaredInputs:       "Fehler: %s hat nicht erlaubte Eingaben gelesen:\n",
+		msgWatchFiles:             "Fehler: Dateien konnten nicht überwacht werden: %s\n",
+		msgWatchFile:              "Fehler: %s konnte nicht überwacht werden: %s\n",
+		msgListIncludes:           "Fehler: Includes konnten nicht aufgelistet werden: %s\n",
+		msgWriteSite:              "Fehler: Website konnte nicht geschrieben werden: %s\n",
+		msgReadMetadata:           "Fehler: Metadaten konnten nicht gelesen werden: %s\n",
+		msgReadDocumentation:      "Fehler: Dokumentation konnte nicht gelesen werden: %s\n",
+		msgFixFile:                "Fehler: %s konnte nicht korrigiert werden: %s\n",
+		msgLoadLintConfig:         "Fehler: Lint-Konfiguration konnte nicht geladen werden: %s\n",
+		msgLintFile:               "Fehler: %s konnte nicht geprüft werden: %s\n",
+		msgWriteBaseline:          "Fehler: Baseline konnte nicht geschrieben werden: %s\n",
+		msgReadBaseline:           "Fehler: Baseline konnte nicht gelesen werden: %s\n",
+		msgRefactor:               "Fehler: Refactoring fehlgeschlagen: %s\n",
+		msgRename:                 "Fehler: Umbenennen fehlgeschlagen: %s\n",
+		msgCheckReleases:          "Fehler: Releases konnten nicht geprüft werden: %s\n",
+		msgInvalidRelease:         "Fehler: Das neueste Release hat eine ungültige Version: %s\n",
+		msgFindBinary:             "Fehler: Die scl-Programmdatei wurde nicht gefunden: %s\n",
+		msgUpdateSCL:              "Fehler: scl konnte nicht aktualisiert werden: %s\n",
+		msgReadStats:              "Fehler: Statistiken konnten nicht gelesen werden: %s\n",
+		msgFindOPA:                "Fehler: Die opa-Programmdatei wurde nicht gefunden: %s\n",
+		msgListChangedFiles:       "Geänderte Dateien konnten nicht aufgelistet werden: %s\n",
+		msgListCoverageStatements: "Bibliotheksanweisungen für die Abdeckung konnten nicht aufgelistet werden: %s\n",
+		msgWriteCoverage:          "Abdeckungsbericht konnte nicht geschrieben werden: %s\n",
+		msgGetPath:                "Pfad konnte nicht ermittelt werden: %s\n",
+		msgReadMirrors:            "Spiegel konnten nicht gelesen werden: %s\n",
+		msgCreatePath:             "Pfad %s konnte nicht erstellt werden: %s\n",
+		msgCreateFile:             "%s konnte nicht erstellt werden: %s\n",
+		msgFileExists:             "%s konnte nicht erstellt werden: Datei existiert bereits\n",
+		msgCreateRepo:             "[%s] Repository konnte nicht erstellt werden: %s\n",
+		msgCheckDependency:        "[%s] Aktualisierungen konnten nicht geprüft werden: %s\n",
+		msgUpdateRepo:             "[%s] Repository konnte nicht aktualisiert werden: %s\n",
+		msgFetchRepo:              "[%s] Repository konnte nicht abgerufen werden: %s\n",
+		msgVerificationFailed:     "[%s] Überprüfung fehlgeschlagen: %s\n",
+		msgFailCreateParser:       "Parser im aktuellen Verzeichnis konnte nicht erstellt werden: %s",
+		msgFailParseFile:          "Datei konnte nicht geparst werden: %s",
+		msgFailRenderJSON:         "JSON konnte nicht erzeugt werden: %s",
+		msgFailReadFile:           "Datei konnte nicht gelesen werden: %s",
+		msgFailReadExpected:       "%s-Datei konnte nicht gelesen werden: %s",
+		msgFailCompare:            "Ausgabe konnte nicht mit der %s-Datei verglichen werden: %s",
+		msgFailDiff:               "Vergleich fehlgeschlagen (%s):",
+		msgFailMutation:           "Ausgabe stimmt trotz Veränderung noch überein: %s",
+		msgFailEvaluatePolicies:   "Richtlinien konnten nicht ausgewertet werden: %s",
+		msgFailDenied:             "Von einer Richtlinie abgelehnt:",
+		msgSkipNoExpectation:      "keine .hcl- oder .json-Datei",
+		msgStampWithJSON:          "--stamp kann nicht mit --json verwendet werden, da JSON keine Kommentare hat\n",
+		msgBaselineRequired:       "--write-baseline erfordert eine --baseline-Datei\n",
+		msgFixRequired:            "Eine Korrektur ist erforderlich. Siehe `scl help fix` für die Syntax",
+		msgFromToRequired:         "--from und --to sind beide erforderlich. Siehe `scl help fix` für die Syntax",
+		msgUnknownFix:             "Unbekannte Korrektur %s. Siehe `scl help fix` für die Syntax",
+		msgRefactoringRequired:    "Ein Refactoring ist erforderlich. Siehe `scl help refactor` für die Syntax",
+		msgUnknownRefactoring:     "Unbekanntes Refactoring %s. Siehe `scl help refactor` für die Syntax",
+		msgRenameNamesRequired:    "Der alte und der neue Mixin-Name sind erforderlich. Siehe `scl help rename` für die Syntax",
+		msgSearchTermRequired:     "Ein Suchbegriff ist erforderlich. Siehe `scl help search` für die Syntax",
+		msgPolicyRequired:         "Mindestens eine --policy ist erforderlich. Siehe `scl help vet` für die Syntax",
+		msgGlobRequired:           "Mindestens ein Dateimuster ist erforderlich. Siehe `scl help test` für die Syntax",
+		msgDependencyRequired:     "Mindestens eine Abhängigkeit ist erforderlich. Siehe `scl help get` für die Syntax",
+		msgLibraryNameRequired:    "Genau ein Bibliotheksname ist erforderlich. Siehe `scl help new-lib` für die Syntax\n",
+		msgInvalidLibraryName:     "Ungültiger Bibliotheksname: %s\n",
+		msgInvalidRunExpression:   "Ungültiger --run-Ausdruck: %s\n",
+		msgInvalidFailures:        "Ungültige Anzahl von Fehlschlägen: %s\n",
+		msgInvalidRetries:         "Ungültige Anzahl von Wiederholungen: %s\n",
+		msgInvalidTests:           "Ungültige Anzahl von Tests: %s\n",
+		msgInvalidTimeout:         "Ungültiges Zeitlimit: %s\n",
+		msgInvalidDuration:        "Ungültige Dauer: %s\n",
+		msgInvalidCaseInsensitive: "Ungültiger Wert für --case-insensitive-includes: %s",
+		msgWrote:                  "%s geschrieben\n",
+		msgWatching:               "%d Datei(en) werden auf Änderungen überwacht\n",
+		msgRunningAgain:           "\n%s: %s, erneute Ausführung\n\n",
+		msgStopped:                "\nAbgebrochen: %s\n",
+		msgFixed:                  "%s korrigiert\n",
+		msgDocumented:             "%d Bibliotheken in %s dokumentiert\n",
+		msgRecordedFindings:       "%d Befund(e) in %s gespeichert\n",
+		msgExtracted:              "%s: %s extrahiert und %d Block/Blöcke ersetzt\n",
+		msgInlined:                "%s: %d Aufruf(e) eingesetzt\n",
+		msgRenamed:                "%s: %d Verweis(e)\n",
+		msgNoReferences:           "Keine Verweise auf %s gefunden\n",
+		msgNoMatchingMixins:       "Keine Mixins passen zu %q\n",
+		msgSCLUpToDate:            "scl %s ist aktuell\n",
+		msgSCLAvailable:           "scl %s ist verfügbar (installiert: %s)\n",
+		msgSCLUpdated:             "scl von %s auf %s aktualisiert\n",
+		msgStatsDisabled:          "Statistiken werden nicht aufgezeichnet. Setzen Sie %s=1, um sie in ~/.scl/stats.jsonl aufzuzeichnen, oder %s, um die Datei zu wählen\n",
+		msgNoStats:                "Noch keine Statistiken in %s aufgezeichnet\n",
+		msgNoCommandsRecorded:     "In diesem Zeitraum wurden keine Befehle aufgezeichnet\n",
+		msgInputsReadBy:           "Von %s gelesene Eingaben:\n",
+		msgNoInputs:               "\t(keine)\n",
+		msgDependencyUpToDate:     "%s %s ist aktuell\n",
+		msgDependencyUpdate:       "%s %s -> %s\n",
+		msgNotPresent:             "[%s] nicht vorhanden, ohne --check ausführen, um es abzurufen\n",
+		msgAlreadyPresent:         "[%s] bereits vorhanden, mit -u ausführen, um es zu aktualisieren\n",
+		msgRetrying:               "[%s] Versuch %d fehlgeschlagen, neuer Versuch: %s\n",
+		msgDependencyUpdated:      "%s erfolgreich aktualisiert\n",
+		msgDependencyFetched:      "%s erfolgreich abgerufen.\n",
+		msgProgressUpdating:       "wird aktualisiert",
+		msgProgressFetching:       "wird abgerufen",
+		msgProgressUpdated:        "aktualisiert",
+		msgProgressFetched:        "abgerufen",
+		msgProgressFailed:         "fehlgeschlagen",
+		msgDependents:             "\n%d Datei(en) binden %s ein\n",
+		msgIgnoredFindings:        "\n%d bestehende(r) Befund(e) in %s ignoriert\n",
+		msgUpdatesAvailable:       "\nFertig. %d Aktualisierung(en) verfügbar.\n",
+		msgGetDone:                "\nFertig. %d Abhängigkeit(en) erstellt, %d Abhängigkeit(en) aktualisiert.\n",
+		msgFailedVerification:     "\n[FAIL] %d Abhängigkeit(en) haben die Überprüfung nicht bestanden\n",
+		msgStoppedAfter:           "\nNach %d Fehlschlag/Fehlschlägen abgebrochen; %d Datei(en) nicht ausgeführt\n",
+		msgNotRunUnchanged:        "\n%d Test(s) nicht ausgeführt: seit %s unverändert\n",
+		msgFlaky:                  "\n%d Test(s) erst bei einer Wiederholung bestanden:\n",
+		msgSlowestTests:           "\nLangsamste %d Test(s):\n",
+		msgTimePerDirectory:       "\nZeit pro Verzeichnis:\n",
+		msgCoverageNone:           "\nAbdeckung: keine Mixins oder Includes außerhalb der Testdateien verwendet\n",
+		msgCoverageFile:           "Abdeckung: %5.1f%% von %d Anweisung(en) in %s\n",
+		msgCoverageTotal:          "Abdeckung: %5.1f%% von %d Anweisung(en) insgesamt\n",
+		msgNotCovered:             "\nNicht abgedeckt:\n",
+	},
+	"fr": {
+		msgLoadParams:       "Erreur : impossible de charger les paramètres : %s\n",
+		msgLoadWorkspace:    "Erreur : impossible de charger l'espace de travail : %s\n",
+		msgCreateParser:     "Erreur : impossible de créer un analyseur dans le répertoire courant : %s\n",
+		msgReadFile:         "Erreur : impossible de lire le fichier : %s\n",
+		msgWriteFile:        "Erreur : impossible d'écrire le fichier : %s\n",
+		msgListFiles:        "Erreur : impossible de lister les fichiers : %s\n",
+		msgIndexLibraries:   "Erreur : impossible d'indexer les bibliothèques : %s\n",
+		msgWarning:          "Avertissement : %s\n",
+		msgFilenameRequired: "Au moins un nom de fichier est requis. Voir `scl help %s` pour la syntaxe",
+		msgFailedErrors:     "\n[FAIL] %d erreur(s)\n",
+		msgFailedFindings:   "\n[FAIL] %d problème(s)\n",
+		msgFailedFiles:      "\n[FAIL] %d fichier(s) sur %d en échec :\n",
+		msgLintUnusedMixin:  "Le mixin privé %s n'est jamais appelé",
+		msgLintUndocumented: "Le mixin %s n'est pas documenté",
+
+		msgError:                  "Erreur : %s\n",
+		msgParseFile:              "Erreur : impossible d'analyser le fichier : %s\n",
+		msgParsePanic:             "Erreur : impossible d'analyser le fichier : %s : %v\n",
+		msgRenderJSON:             "Erreur : impossible de générer le JSON : %s\n",
+		msgUndeclaredInputs:       "Erreur : %s a lu des entrées non autorisées :\n",
+		msgWatchFiles:             "Erreur : impossible de surveiller les fichiers : %s\n",
+		msgWatchFile:              "Erreur : impossible de surveiller %s : %s\n",
+		msgListIncludes:           "Erreur : impossible de lister les inclusions : %s\n",
+		msgWriteSite:              "Erreur : impossible d'écrire le site : %s\n",
+		msgReadMetadata:           "Erreur : impossible de lire les métadonnées : %s\n",
+		msgReadDocumentation:      "Erreur : impossible de lire la documentation : %s\n",
+		msgFixFile:                "Erreur : impossible de corriger %s : %s\n",
+		msgLoadLintConfig:         "Erreur : impossible de charger la configuration de lint : %s\n",
+		msgLintFile:               "Erreur : impossible de vérifier %s : %s\n",
+		msgWriteBaseline:          "Erreur : impossible d'écrire la baseline : %s\n",
+		msgReadBaseline:           "Erreur : impossible de lire la baseline : %s\n",
+		msgRefactor:               "Erreur : impossible de refactoriser : %s\n",
+		msgRename:                 "Erreur : impossible de renommer : %s\n",
+		msgCheckReleases:          "Erreur : impossible de vérifier les versions publiées : %s\n",
+		msgInvalidRelease:         "Erreur : la dernière version publiée a un numéro invalide : %s\n",
+		msgFindBinary:             "Erreur : impossible de trouver l'exécutable scl : %s\n",
+		msgUpdateSCL:              "Erreur : impossible de mettre à jour scl : %s\n",
+		msgReadStats:              "Erreur : impossible de lire les statistiques : %s\n",
+		msgFindOPA:                "Erreur : impossible de trouver l'exécutable opa : %s\n",
+		msgListChangedFiles:       "Impossible de lister les fichiers modifiés : %s\n",
+		msgListCoverageStatements: "Impossible de lister les instructions des bibliothèques pour la couverture : %s\n",
+		msgWriteCoverage:          "Impossible d'écrire le rapport de couverture : %s\n",
+		msgGetPath:                "Impossible d'obtenir le chemin : %s\n",
+		msgReadMirrors:            "Impossible de lire les miroirs : %s\n",
+		msgCreatePath:             "Impossible de créer le chemin %s : %s\n",
+		msgCreateFile:             "Impossible de créer %s : %s\n",
+		msgFileExists:             "Impossible de créer %s : le fichier existe déjà\n",
+		msgCreateRepo:             "[%s] Impossible de créer le dépôt : %s\n",
+		msgCheckDependency:        "[%s] Impossible de vérifier les mises à jour : %s\n",
+		msgUpdateRepo:             "[%s] Impossible de mettre à jour le dépôt : %s\n",
+		msgFetchRepo:              "[%s] Impossible de récupérer le dépôt : %s\n",
+		msgVerificationFailed:     "[%s] Échec de la vérification : %s\n",
+		msgFailCreateParser:       "Impossible de créer un analyseur dans le répertoire courant : %s",
+		msgFailParseFile:          "Impossible d'analyser le fichier : %s",
+		msgFailRenderJSON:         "Impossible de générer le JSON : %s",
+		msgFailReadFile:           "Impossible de lire le fichier : %s",
+		msgFailReadExpected:       "Impossible de lire le fichier %s : %s",
+		msgFailCompare:            "Impossible de comparer la sortie avec le fichier %s : %s",
+		msgFailDiff:               "Échec de la comparaison (%s) :",
+		msgFailMutation:           "La sortie correspond toujours après perturbation : %s",
+		msgFailEvaluatePolicies:   "Impossible d'évaluer les politiques : %s",
+		msgFailDenied:             "Refusé par une politique :",
+		msgSkipNoExpectation:      "aucun fichier .hcl ou .json",
+		msgStampWithJSON:          "--stamp ne peut pas être utilisé avec --json, qui n'a pas de commentaires\n",
+		msgBaselineRequired:       "--write-baseline nécessite un fichier --baseline\n",
+		msgFixRequired:            "Une correction est requise. Voir `scl help fix` pour la syntaxe",
+		msgFromToRequired:         "--from et --to sont tous deux requis. Voir `scl help fix` pour la syntaxe",
+		msgUnknownFix:             "Correction inconnue %s. Voir `scl help fix` pour la syntaxe",
+		msgRefactoringRequired:    "Une refactorisation est requise. Voir `scl help refactor` pour la syntaxe",
+		msgUnknownRefactoring:     "Refactorisation inconnue %s. Voir `scl help refactor` pour la syntaxe",
+		msgRenameNamesRequired:    "L'ancien et le nouveau nom du mixin sont requis. Voir `scl help rename` pour la syntaxe",
+		msgSearchTermRequired:     "Un terme de recherche est requis. Voir `scl help search` pour la syntaxe",
+		msgPolicyRequired:         "Au moins une --policy est requise. Voir `scl help vet` pour la syntaxe",
+		msgGlobRequired:           "Au moins un motif de fichiers est requis. Voir `scl help test` pour la syntaxe",
+		msgDependencyRequired:     "Au moins une dépendance est requise. Voir `scl help get` pour la syntaxe",
+		msgLibraryNameRequired:    "Un seul nom de bibliothèque est requis. Voir `scl help new-lib` pour la syntaxe\n",
+		msgInvalidLibraryName:     "Nom de bibliothèque invalide : %s\n",
+		msgInvalidRunExpression:   "Expression --run invalide : %s\n",
+		msgInvalidFailures:        "Nombre d'échecs invalide : %s\n",
+		msgInvalidRetries:         "Nombre de nouvelles tentatives invalide : %s\n",
+		msgInvalidTests:           "Nombre de tests invalide : %s\n",
+		msgInvalidTimeout:         "Délai invalide : %s\n",
+		msgInvalidDuration:        "Durée invalide : %s\n",
+		msgInvalidCaseInsensitive: "Valeur de --case-insensitive-includes invalide : %s",
+		msgWrote:                  "%s écrit\n",
+		msgWatching:               "Surveillance de %d fichier(s)\n",
+		msgRunningAgain:           "\n%s : %s, nouvelle exécution\n\n",
+		msgStopped:                "\nArrêté : %s\n",
+		msgFixed:                  "%s corrigé\n",
+		msgDocumented:             "%d bibliothèques documentées dans %s\n",
+		msgRecordedFindings:       "%d problème(s) enregistré(s) dans %s\n",
+		msgExtracted:              "%s : %s extrait et %d bloc(s) remplacé(s)\n",
+		msgInlined:                "%s : %d appel(s) remplacé(s) par le corps\n",
+		msgRenamed:                "%s : %d référence(s)\n",
+		msgNoReferences:           "Aucune référence à %s trouvée\n",
+		msgNoMatchingMixins:       "Aucun mixin ne correspond à %q\n",
+		msgSCLUpToDate:            "scl %s est à jour\n",
+		msgSCLAvailable:           "scl %s est disponible (installé : %s)\n",
+		msgSCLUpdated:             "scl mis à jour de %s vers %s\n",
+		msgStatsDisabled:          "Les statistiques ne sont pas enregistrées. Définissez %s=1 pour les enregistrer dans ~/.scl/stats.jsonl, ou %s pour choisir le fichier\n",
+		msgNoStats:                "Aucune statistique enregistrée dans %s pour l'instant\n",
+		msgNoCommandsRecorded:     "Aucune commande enregistrée sur cette période\n",
+		msgInputsReadBy:           "Entrées lues par %s :\n",
+		msgNoInputs:               "\t(aucune)\n",
+		msgDependencyUpToDate:     "%s %s est à jour\n",
+		msgDependencyUpdate:       "%s %s -> %s\n",
+		msgNotPresent:             "[%s] absent, lancez sans --check pour le récupérer\n",
+		msgAlreadyPresent:         "[%s] déjà présent, lancez avec -u pour le mettre à jour\n",
+		msgRetrying:               "[%s] Tentative %d échouée, nouvel essai : %s\n",
+		msgDependencyUpdated:      "%s mis à jour avec succès\n",
+		msgDependencyFetched:      "%s récupéré avec succès.\n",
+		msgProgressUpdating:       "mise à jour",
+		msgProgressFetching:       "récupération",
+		msgProgressUpdated:        "mis à jour",
+		msgProgressFetched:        "récupéré",
+		msgProgressFailed:         "échec",
+		msgDependents:             "\n%d fichier(s) incluent %s\n",
+		msgIgnoredFindings:        "\n%d problème(s) existant(s) dans %s ignoré(s)\n",
+		msgUpdatesAvailable:       "\nTerminé. %d mise(s) à jour disponible(s).\n",
+		msgGetDone:                "\nTerminé. %d dépendance(s) créée(s), %d dépendance(s) mise(s) à jour.\n",
+		msgFailedVerification:     "\n[FAIL] %d dépendance(s) ont échoué à la vérification\n",
+		msgStoppedAfter:           "\nArrêté après %d échec(s) ; %d fichier(s) non exécuté(s)\n",
+		msgNotRunUnchanged:        "\n%d test(s) non exécuté(s) : inchangé(s) depuis %s\n",
+		msgFlaky:                  "\n%d test(s) réussi(s) seulement après une nouvelle tentative :\n",
+		msgSlowestTests:           "\n%d test(s) les plus lents :\n",
+		msgTimePerDirectory:       "\nTemps par répertoire :\n",
+		msgCoverageNone:           "\ncouverture : aucun mixin ni inclusion utilisé hors des fichiers de test\n",
+		msgCoverageFile:           "couverture : %5.1f%% de %d instruction(s) dans %s\n",
+		msgCoverageTotal:          "couverture : %5.1f%% de %d instruction(s) au total\n",
+		msgNotCovered:             "\nNon couvert :\n",
+	},
+}
+
+// messageLanguage returns the catalogue language chosen with SCL_LANG, or
+// English if there's no catalogue for it.
+func messageLanguage() string {
+
+	language := strings.ToLower(os.Getenv(languageVariable))
+
+	// Reduce a locale such as de_DE.UTF-8 to its language
+	if i := strings.IndexAny(language, "_-."); i >= 0 {
+		language = language[:i]
+	}
+
+	if _, ok := messageCatalogue[language]; ok {
+		return language
+	}
+
+	return defaultLanguage
+}
+
+// localise formats a message in the language chosen with SCL_LANG.
+func localise(id messageID, args ...interface{}) string {
+	return localiseIn(messageLanguage(), id, args...)
+}
+
+// localiseIn formats a message in a particular language, falling back to
+// English if it hasn't been translated.
+func localiseIn(language string, id messageID, args ...interface{}) string {
+
+	format, ok := messageCatalogue[language][id]
+
+	if !ok {
+		format = messageCatalogue[defaultLanguage][id]
+	}
+
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type mirror struct {
+	prefix      string
+	replacement string
+}
+
+// mirrorMap rewrites dependency URLs so they can be fetched from a mirror
+// rather than their upstream host. The longest matching prefix wins.
+type mirrorMap []mirror
+
+/*
+loadMirrors reads a mirror mapping file. Each non-empty line holds a prefix and
+its replacement, separated by whitespace, and lines starting with # are ignored:
+
+	# Fetch everything from GitHub via the internal mirror
+	github.com/org  git.internal.example.com/mirror/org
+*/
+func loadMirrors(path string) (mirrors mirrorMap, err error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a prefix and a replacement", path, lineNumber)
+		}
+
+		mirrors = append(mirrors, mirror{
+			prefix:      strings.TrimSuffix(fields[0], "/"),
+			replacement: strings.TrimSuffix(fields[1], "/"),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return len(mirrors[i].prefix) > len(mirrors[j].prefix)
+	})
+
+	return mirrors, nil
+}
+
+func (m mirrorMap) rewrite(dep string) string {
+
+	for _, mirror := range m {
+		if dep == mirror.prefix || strings.HasPrefix(dep, mirror.prefix+"/") {
+			return mirror.replacement + strings.TrimPrefix(dep, mirror.prefix)
+		}
+	}
+
+	return dep
+}
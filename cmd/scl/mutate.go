@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const mutationSuffix = "-scl-mutant"
+
+/*
+mutate re-runs a passing test case once for each parameter it should depend
+on, with that parameter's value perturbed. If the expected output still
+matches, the golden files can't tell the difference and the parameter is
+returned as a survivor.
+
+The parameters are the ones named in the case's scl:mutate comments or, if it
+has none, every parameter given with --param. Environment variables are never
+perturbed unless they're named explicitly.
+*/
+func (r testRunner) mutate(tc testCase, params []string) (survivors []string) {
+
+	if len(tc.mutations) > 0 {
+		params = tc.mutations
+	}
+
+	for _, name := range params {
+		if result := r.withParam(name, mutatedValue(r.param(name))).run(tc); result.status == testPassed {
+			survivors = append(survivors, name)
+		}
+	}
+
+	return
+}
+
+// param returns the value a runner passes to the parser for a parameter or
+// environment variable, or an empty string if it isn't set.
+func (r testRunner) param(name string) string {
+
+	value := ""
+
+	if env, ok := r.environment.Lookup(name); ok {
+		value = `"` + strings.TrimSpace(env) + `"`
+	}
+
+	for _, p := range r.params {
+		if p.name == name {
+			value = p.value
+		}
+	}
+
+	return value
+}
+
+// withParam returns a copy of the runner with a parameter replaced, leaving
+// the original runner's parameters untouched.
+func (r testRunner) withParam(name, value string) testRunner {
+
+	params := make(paramSlice, 0, len(r.params)+1)
+
+	for _, p := range r.params {
+		if p.name != name {
+			params = append(params, p)
+		}
+	}
+
+	r.params = append(params, &param{name: name, value: value})
+
+	return r
+}
+
+// mutatedValue perturbs a quoted parameter value, keeping it quoted so that
+// it's still valid wherever the original was used.
+func mutatedValue(value string) string {
+
+	if value == "" {
+		return fmt.Sprintf(`"%s"`, strings.TrimPrefix(mutationSuffix, "-"))
+	}
+
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[:len(value)-1] + mutationSuffix + `"`
+	}
+
+	return value + mutationSuffix
+}
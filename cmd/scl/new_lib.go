@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/tucnak/climax"
+)
+
+var libraryNameMatcher = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+$`)
+var mixinNameCleaner = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// The files of a new library, keyed by their path relative to the library
+var libraryTemplates = []struct {
+	path    string
+	content string
+}{
+	{
+		path: "scl.lib",
+		content: `name = "{{.Name}}"
+version = "0.1.0"
+description = "Describe what {{.Short}} configures"
+`,
+	},
+	{
+		path: "{{.Short}}.scl",
+		content: `/*
+  {{.Mixin}} is an example mixin. Replace it with the mixins your library
+  provides, documenting each of them with a comment like this one; the
+  comment is shown as the mixin's documentation.
+
+  ` + "```" + `
+  {{.Mixin}}("name")
+      extra = "attributes"
+  ` + "```" + `
+*/
+@{{.Mixin}}($name, $value="default")
+    {{.Mixin}} $name
+        value = $value
+        __body__()
+`,
+	},
+	{
+		path: "tests/{{.Short}}.scl",
+		content: `include("{{.Short}}")
+
+{{.Mixin}}("example")
+    extra = "attribute"
+`,
+	},
+	{
+		path: "tests/{{.Short}}.hcl",
+		content: `{{.Mixin}} "example" {
+  value = "default"
+  extra = "attribute"
+}
+`,
+	},
+	{
+		path: "README.md",
+		content: `# {{.Name}}
+
+Describe what {{.Short}} configures, and how to use it.
+
+## Usage
+
+` + "```" + `
+include("{{.Name}}/{{.Short}}")
+
+{{.Mixin}}("example")
+` + "```" + `
+
+## Testing
+
+Run the golden tests from this directory:
+
+` + "```" + `
+$ scl test tests/*.scl
+` + "```" + `
+`,
+	},
+}
+
+type libraryDetails struct {
+	Name  string
+	Short string
+	Mixin string
+}
+
+func newLibCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "new-lib",
+		Brief: "Create the skeleton of a new library",
+		Usage: `[options] <name>`,
+		Help:  "Create a new library, with a manifest, a documented example mixin and a golden test that passes with `scl test`. The name is the library's import path, such as github.com/myorg/network.",
+
+		Flags: []climax.Flag{
+			{
+				Name:     "output-path",
+				Short:    "o",
+				Usage:    `--output-path /path/to/library`,
+				Help:     `The directory to create the library in. Default is the last element of the name`,
+				Variable: true,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) != 1 {
+				fmt.Fprint(stderr, localise(msgLibraryNameRequired))
+				return 1
+			}
+
+			name := strings.Trim(ctx.Args[0], "/")
+
+			if !libraryNameMatcher.MatchString(name) {
+				fmt.Fprint(stderr, localise(msgInvalidLibraryName, name))
+				return 1
+			}
+
+			details := libraryDetails{
+				Name:  name,
+				Short: path.Base(name),
+				Mixin: strings.Trim(mixinNameCleaner.ReplaceAllString(path.Base(name), "_"), "_"),
+			}
+
+			if details.Mixin == "" {
+				details.Mixin = "example"
+			}
+
+			dir := details.Short
+
+			if outputPath, set := ctx.Get("output-path"); set {
+				dir = outputPath
+			}
+
+			for _, file := range libraryTemplates {
+
+				filePath, err := renderLibraryTemplate(file.path, details)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgCreateFile, file.path, err.Error()))
+					return 1
+				}
+
+				filePath = filepath.Join(dir, filePath)
+
+				content, err := renderLibraryTemplate(file.content, details)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgCreateFile, filePath, err.Error()))
+					return 1
+				}
+
+				if _, err := os.Stat(filePath); err == nil {
+					fmt.Fprint(stderr, localise(msgFileExists, filePath))
+					return 1
+				}
+
+				if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+					fmt.Fprint(stderr, localise(msgCreatePath, filepath.Dir(filePath), err.Error()))
+					return 1
+				}
+
+				if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+					fmt.Fprint(stderr, localise(msgCreateFile, filePath, err.Error()))
+					return 1
+				}
+
+				fmt.Fprintln(stdout, filePath)
+			}
+
+			return 0
+		},
+	}
+}
+
+func renderLibraryTemplate(text string, details libraryDetails) (string, error) {
+
+	t, err := template.New("").Parse(text)
+
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	if err := t.Execute(&b, details); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
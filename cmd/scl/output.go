@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A fileOutput collects everything written about a single file, so that it
+// can be written out in one piece once the file has been dealt with.
+type fileOutput struct {
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	failures int
+}
+
+// fail writes a FAIL line to the file's error output and counts it.
+func (f *fileOutput) fail(path string, err string, args ...interface{}) {
+	fmt.Fprintf(&f.stderr, "%-7s %s %s\n", "FAIL", path, fmt.Sprintf(err, args...))
+	f.failures++
+}
+
+/*
+A syncOutput writes fileOutputs to the real stdout and stderr. Each file's
+output is written under a lock, so that the output of files which are handled
+concurrently is never interleaved.
+*/
+type syncOutput struct {
+	mutex  sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func newSyncOutput(stdout, stderr io.Writer) *syncOutput {
+	return &syncOutput{stdout: stdout, stderr: stderr}
+}
+
+func (o *syncOutput) flush(f *fileOutput) {
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	f.stdout.WriteTo(o.stdout)
+	f.stderr.WriteTo(o.stderr)
+}
+
+// outputFileName is where run --output-dir writes the output of a file: the
+// same path within the output directory, with a .hcl or .json extension. A
+// file outside the working directory is written to the top of the output
+// directory.
+func outputFileName(outputDir, fileName string, json bool) string {
+
+	name := filepath.Clean(fileName)
+
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+		name = filepath.Base(name)
+	}
+
+	extension := ".hcl"
+
+	if json {
+		extension = ".json"
+	}
+
+	return filepath.Join(outputDir, strings.TrimSuffix(name, filepath.Ext(name))+extension)
+}
+
+// checkOutputFileNames makes sure that no two files would be written to the
+// same output file, which happens when files outside the working directory
+// share a name.
+func checkOutputFileNames(outputDir string, fileNames []string, json bool) error {
+
+	written := make(map[string]string)
+
+	for _, fileName := range fileNames {
+
+		name := outputFileName(outputDir, fileName, json)
+
+		if other, ok := written[name]; ok && filepath.Clean(other) != filepath.Clean(fileName) {
+			return fmt.Errorf("%s and %s would both be written to %s", other, fileName, name)
+		}
+
+		written[name] = fileName
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+// outputFormatParams are the flags shared by the commands which write HCL, so
+// that the same layout can be used everywhere.
+func outputFormatParams() []climax.Flag {
+
+	return []climax.Flag{
+		{
+			Name:     "indent",
+			Usage:    `--indent 4`,
+			Help:     `The number of spaces, or tabs with --indent-tabs, to indent each level of the output by. Default is 2 spaces or 1 tab`,
+			Variable: true,
+		},
+		{
+			Name:  "indent-tabs",
+			Usage: `--indent-tabs`,
+			Help:  `Indent the output with tabs rather than spaces`,
+		},
+		{
+			Name:  "align",
+			Usage: `--align`,
+			Help:  `Line up the equals signs of consecutive attributes in the output`,
+		},
+		{
+			Name:     "max-width",
+			Usage:    `--max-width 100`,
+			Help:     `Wrap attributes with list values longer than this many characters, one item per line`,
+			Variable: true,
+		},
+	}
+}
+
+func outputFormat(ctx climax.Context) (format scl.OutputFormat, err error) {
+
+	format.IndentWithTabs = ctx.Is("indent-tabs")
+	format.AlignAttributes = ctx.Is("align")
+
+	if indent, set := ctx.Get("indent"); set {
+		if format.IndentWidth, err = strconv.Atoi(indent); err != nil || format.IndentWidth < 1 {
+			return format, fmt.Errorf("Invalid indent: %s", indent)
+		}
+	}
+
+	if width, set := ctx.Get("max-width"); set {
+		if format.MaxLineWidth, err = strconv.Atoi(width); err != nil || format.MaxLineWidth < 1 {
+			return format, fmt.Errorf("Invalid maximum width: %s", width)
+		}
+	}
+
+	return format, nil
+}
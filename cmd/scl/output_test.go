@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FilesMustNotBeWrittenToTheSameOutputFile(t *testing.T) {
+
+	for cycle, input := range []struct {
+		fileNames []string
+		err       error
+	}{
+		{
+			fileNames: []string{"a/x.scl", "b/x.scl", "a/x.scl"},
+		},
+		{
+			fileNames: []string{"../a/x.scl", "../b/x.scl"},
+			err:       fmt.Errorf("../a/x.scl and ../b/x.scl would both be written to out/x.hcl"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.err, checkOutputFileNames("out", input.fileNames, false))
+	}
+}
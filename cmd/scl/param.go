@@ -7,8 +7,9 @@ import (
 )
 
 type param struct {
-	name  string
-	value string
+	name      string
+	value     string
+	sensitive bool
 }
 
 func (p param) String() string {
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ageHeader        = "age-encryption.org/v1"
+	ageArmoredHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+/*
+loadParamFile reads parameters from a JSON object of names and values. The
+file may be encrypted with SOPS or age, in which case it's decrypted with the
+sops or age binary first and all of its values are marked as sensitive.
+
+Keys for sops are found by sops itself. For age, the identity file is taken
+from SCL_AGE_IDENTITY_FILE or, failing that, SOPS_AGE_KEY_FILE, so that the
+same key can be used for both.
+*/
+func loadParamFile(path string) (params paramSlice, err error) {
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := true
+
+	switch {
+	case isSOPSParamFile(content):
+		content, err = decryptCommand(sopsBinary(), "--decrypt", "--output-type", "json", path)
+
+	case bytes.HasPrefix(content, []byte(ageHeader)), bytes.HasPrefix(bytes.TrimSpace(content), []byte(ageArmoredHeader)):
+
+		identity := os.Getenv("SCL_AGE_IDENTITY_FILE")
+
+		if identity == "" {
+			identity = os.Getenv("SOPS_AGE_KEY_FILE")
+		}
+
+		if identity == "" {
+			return nil, fmt.Errorf("%s is encrypted with age, but neither SCL_AGE_IDENTITY_FILE nor SOPS_AGE_KEY_FILE is set", path)
+		}
+
+		content, err = decryptCommand("age", "--decrypt", "--identity", identity, path)
+
+	default:
+		encrypted = false
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("Can't decode %s: %s", path, err)
+	}
+
+	names := make([]string, 0, len(values))
+
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		p := &param{name: name}
+
+		switch v := values[name].(type) {
+
+		case string:
+			p.value = strconv.Quote(v)
+
+		case float64, bool:
+			p.value = fmt.Sprint(v)
+
+		default:
+			return nil, fmt.Errorf("Can't decode %s: %s must be a string, number or boolean", path, name)
+		}
+
+		p.sensitive = encrypted
+
+		params = append(params, p)
+	}
+
+	return params, nil
+}
+
+// isSOPSParamFile reports whether a JSON file has been encrypted with SOPS,
+// which adds a "sops" metadata key to the document.
+func isSOPSParamFile(content []byte) bool {
+
+	var document map[string]json.RawMessage
+
+	if err := json.Unmarshal(content, &document); err != nil {
+		return false
+	}
+
+	_, ok := document["sops"]
+
+	return ok
+}
+
+func decryptCommand(name string, args ...string) ([]byte, error) {
+
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("The %s binary is needed to decrypt %s, but it can't be found", name, args[len(args)-1])
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", name, msg)
+		}
+
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+const defaultPolicyQuery = "data.scl.deny"
+
+/*
+A policyEvaluator checks rendered output against Rego policies by running the
+opa binary, so that scl doesn't need to link in the whole policy engine. The
+output is passed as JSON input, in the same form as scl run --json, where each
+block is a list of objects keyed by its labels. The query should produce a set
+or array of denial messages:
+
+	package scl
+
+	deny[msg] {
+	  r := input.resource[_].aws_s3_bucket[_][name][_]
+	  r.acl == "public-read"
+	  msg := sprintf("bucket %s is public", [name])
+	}
+
+A query producing an object is treated as a map of messages, and a query
+producing a single string is a single denial.
+*/
+type policyEvaluator struct {
+	binary   string
+	policies []string
+	query    string
+}
+
+func (e policyEvaluator) evaluate(input []byte) (denials []string, err error) {
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+
+	for _, policy := range e.policies {
+		args = append(args, "--data", policy)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(e.binary, append(args, e.query)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", e.binary, msg)
+		}
+
+		return nil, err
+	}
+
+	result := struct {
+		Result []struct {
+			Expressions []struct {
+				Value interface{} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}{}
+
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("Can't decode %s output: %s", e.binary, err)
+	}
+
+	for _, r := range result.Result {
+		for _, expression := range r.Expressions {
+			denials = append(denials, policyMessages(expression.Value)...)
+		}
+	}
+
+	return denials, nil
+}
+
+// policyMessages flattens the value of a deny query into a list of messages.
+func policyMessages(value interface{}) (messages []string) {
+
+	switch v := value.(type) {
+
+	case nil:
+
+	case bool:
+		// A boolean deny rule carries no message of its own
+		if v {
+			messages = append(messages, "denied by policy")
+		}
+
+	case string:
+		messages = append(messages, v)
+
+	case []interface{}:
+		for _, item := range v {
+			messages = append(messages, policyMessages(item)...)
+		}
+
+	case map[string]interface{}:
+
+		keys := make([]string, 0, len(v))
+
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			for _, m := range policyMessages(v[k]) {
+				messages = append(messages, k+": "+m)
+			}
+		}
+
+	default:
+		messages = append(messages, fmt.Sprint(v))
+	}
+
+	return
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progress reports the status of each repository as scl get works through
+// them. On a terminal the current line is redrawn with the elapsed time while
+// each repository is fetched, so long clones aren't silent.
+type progress struct {
+	w        io.Writer
+	total    int
+	terminal bool
+}
+
+func newProgress(w io.Writer, total int) *progress {
+
+	terminal := false
+
+	if f, ok := w.(*os.File); ok {
+		if stat, err := f.Stat(); err == nil {
+			terminal = stat.Mode()&os.ModeCharDevice != 0
+		}
+	}
+
+	return &progress{w: w, total: total, terminal: terminal}
+}
+
+// start reports that work on the index'th repository has started, and
+// returns a function that reports its final status.
+func (p *progress) start(index int, name, action string) (finish func(status string)) {
+
+	var (
+		started = time.Now()
+		prefix  = fmt.Sprintf("[%d/%d] %s", index+1, p.total, name)
+		done    = make(chan struct{})
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+	)
+
+	if !p.terminal {
+		fmt.Fprintf(p.w, "%s %s...\n", prefix, action)
+	} else {
+
+		fmt.Fprintf(p.w, "%s %s...", prefix, action)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					lock.Lock()
+					fmt.Fprintf(p.w, "\r%s %s... %s", prefix, action, time.Since(started).Truncate(time.Second))
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	return func(status string) {
+
+		close(done)
+		wg.Wait()
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if p.terminal {
+			fmt.Fprint(p.w, "\r\033[K")
+		}
+
+		fmt.Fprintf(p.w, "%s %s (%.1fs)\n", prefix, status, time.Since(started).Seconds())
+	}
+}
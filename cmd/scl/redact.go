@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const redactedValue = "(sensitive)"
+
+// A redactor replaces the values of sensitive parameters wherever they
+// appear in a string.
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+/*
+redactor builds a redactor for the sensitive params. Each value is also
+replaced where it's been written as a quoted string, or inside one with its
+quotes and backslashes escaped, since that's how values usually appear in
+rendered output and error messages.
+*/
+func (ps paramSlice) redactor() redactor {
+
+	replacements := make(map[string]string)
+
+	for _, p := range ps {
+
+		if !p.sensitive {
+			continue
+		}
+
+		v, err := strconv.Unquote(p.value)
+
+		if err != nil {
+			v = p.value
+		}
+
+		if v == "" {
+			continue
+		}
+
+		quoted := strconv.Quote(v)
+
+		replacements[v] = redactedValue
+		replacements[quoted] = strconv.Quote(redactedValue)
+		replacements[quoted[1:len(quoted)-1]] = redactedValue
+	}
+
+	if len(replacements) == 0 {
+		return redactor{}
+	}
+
+	values := make([]string, 0, len(replacements))
+
+	for v := range replacements {
+		values = append(values, v)
+	}
+
+	// Longer values are replaced first, so a value that contains another
+	// is never partly revealed
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	pairs := make([]string, 0, len(values)*2)
+
+	for _, v := range values {
+		pairs = append(pairs, v, replacements[v])
+	}
+
+	return redactor{strings.NewReplacer(pairs...)}
+}
+
+func (r redactor) redact(s string) string {
+
+	if r.replacer == nil {
+		return s
+	}
+
+	return r.replacer.Replace(s)
+}
+
+func (r redactor) postprocess(output []byte) ([]byte, error) {
+	return []byte(r.redact(string(output))), nil
+}
+
+// writer wraps a writer so that everything written to it is redacted. Each
+// write is redacted separately, so values split across writes get through;
+// fileOutputs are written in one piece, so this doesn't happen for them.
+func (r redactor) writer(w io.Writer) io.Writer {
+
+	if r.replacer == nil {
+		return w
+	}
+
+	return redactingWriter{w, r}
+}
+
+type redactingWriter struct {
+	w io.Writer
+	r redactor
+}
+
+func (rw redactingWriter) Write(p []byte) (int, error) {
+
+	if _, err := io.WriteString(rw.w, rw.r.redact(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
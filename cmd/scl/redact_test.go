@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SensitiveParamsAreRedacted(t *testing.T) {
+
+	params := paramSlice{
+		{name: "password", value: `"pa\"ss\\word"`, sensitive: true},
+		{name: "pin", value: "1234", sensitive: true},
+		{name: "user", value: `"admin"`},
+	}
+
+	r := params.redactor()
+
+	for cycle, input := range []struct {
+		input  string
+		output string
+	}{
+		{
+			input:  `pa"ss\word`,
+			output: `(sensitive)`,
+		},
+		{
+			input:  `password = "pa\"ss\\word"`,
+			output: `password = "(sensitive)"`,
+		},
+		{
+			input:  `Unknown value "x pa\"ss\\word y"`,
+			output: `Unknown value "x (sensitive) y"`,
+		},
+		{
+			input:  `pin = 1234`,
+			output: `pin = (sensitive)`,
+		},
+		{
+			input:  `user = "admin"`,
+			output: `user = "admin"`,
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.output, r.redact(input.input))
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+var lineRangeMatcher = regexp.MustCompile(`^(.+):([0-9]+)-([0-9]+)$`)
+
+func refactorCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "refactor",
+		Brief: "Restructure .scl files without changing their output",
+		Usage: `[options] extract-mixin <file.scl:from-to> | inline-mixin <name>`,
+		Help:  "Apply a refactoring to .scl files.\n\nextract-mixin moves a range of lines into a new mixin named by --name, and replaces them with a call to it. With --all, any other blocks in the file with the same shape are replaced with calls too, and literals which differ between the blocks become the mixin's parameters.\n\ninline-mixin replaces every call to a mixin in the files in --scope with the mixin's body, and removes its declaration. Only mixins which don't declare variables or mixins of their own can be inlined.",
+
+		Flags: []climax.Flag{
+			{
+				Name:     "name",
+				Usage:    `--name web_server`,
+				Help:     `The name of the mixin to extract`,
+				Variable: true,
+			},
+			{
+				Name:  "all",
+				Usage: `--all`,
+				Help:  `Also replace the other blocks in the file with the same shape as the extracted one`,
+			},
+			{
+				Name:     "scope",
+				Short:    "s",
+				Usage:    `--scope ./...`,
+				Help:     `Comma-separated list of files and directories for inline-mixin to change. A directory ending in /... includes its subdirectories. Default is ./...`,
+				Variable: true,
+			},
+			{
+				Name:  "dry-run",
+				Short: "n",
+				Usage: `--dry-run`,
+				Help:  `Print the refactored files instead of changing them`,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) == 0 {
+				fmt.Fprint(stderr, localise(msgRefactoringRequired))
+				return 1
+			}
+
+			var (
+				changes []sourceChange
+				err     error
+			)
+
+			switch ctx.Args[0] {
+
+			case "extract-mixin":
+				changes, err = refactorExtractMixin(ctx, stdout)
+
+			case "inline-mixin":
+				changes, err = refactorInlineMixin(ctx, stdout)
+
+			default:
+				fmt.Fprint(stderr, localise(msgUnknownRefactoring, ctx.Args[0]))
+				return 1
+			}
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgRefactor, err.Error()))
+				return 1
+			}
+
+			for _, c := range changes {
+
+				if ctx.Is("dry-run") {
+					fmt.Fprintf(stdout, "/* %s */\n%s\n", c.fileName, c.content)
+					continue
+				}
+
+				if err := writeSourceFile(c.fileName, c.content); err != nil {
+					fmt.Fprint(stderr, localise(msgWriteFile, err.Error()))
+					return 1
+				}
+			}
+
+			return 0
+		},
+	}
+}
+
+func refactorExtractMixin(ctx climax.Context, stdout io.Writer) ([]sourceChange, error) {
+
+	name, set := ctx.Get("name")
+
+	if len(ctx.Args) != 2 || !set {
+		return nil, fmt.Errorf("extract-mixin needs a file:from-to range and a --name")
+	}
+
+	parts := lineRangeMatcher.FindStringSubmatch(ctx.Args[1])
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%s isn't a file:from-to range", ctx.Args[1])
+	}
+
+	fileName := parts[1]
+	from, _ := strconv.Atoi(parts[2])
+	to, _ := strconv.Atoi(parts[3])
+
+	src, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out, replaced, err := scl.ExtractMixin(src, fileName, from, to, name, ctx.Is("all"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprint(stdout, localise(msgExtracted, fileName, name, replaced))
+
+	return []sourceChange{{fileName, out}}, nil
+}
+
+func refactorInlineMixin(ctx climax.Context, stdout io.Writer) ([]sourceChange, error) {
+
+	if len(ctx.Args) != 2 {
+		return nil, fmt.Errorf("inline-mixin needs the name of a mixin")
+	}
+
+	name := ctx.Args[1]
+	scope := []string{"./..."}
+
+	if s, set := ctx.Get("scope"); set {
+		scope = strings.Split(s, ",")
+	}
+
+	files, err := sourceFiles(scope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string][]byte)
+
+	var body *scl.MixinBody
+
+	for _, fileName := range files {
+
+		src, err := ioutil.ReadFile(fileName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sources[fileName] = src
+
+		b, err := scl.FindMixin(src, fileName, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if b != nil && body != nil {
+			return nil, fmt.Errorf("%s is declared in both %s and %s", name, body.File, b.File)
+		}
+
+		if b != nil {
+			body = b
+		}
+	}
+
+	if body == nil {
+		return nil, fmt.Errorf("%s isn't declared in any of the files in scope", name)
+	}
+
+	var changes []sourceChange
+
+	for _, fileName := range files {
+
+		out, inlined, err := scl.InlineMixin(sources[fileName], fileName, body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if inlined > 0 || fileName == body.File {
+			changes = append(changes, sourceChange{fileName, out})
+			fmt.Fprint(stdout, localise(msgInlined, fileName, inlined))
+		}
+	}
+
+	return changes, nil
+}
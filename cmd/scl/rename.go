@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func renameCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "rename",
+		Brief: "Rename a mixin everywhere it's declared and called",
+		Usage: `[options] <old_mixin> <new_mixin>`,
+		Help:  "Rename a mixin in every .scl file in the scope, changing its declarations and calls but not variables, literals or comments with the same name. Vendored libraries are never changed.",
+
+		Flags: []climax.Flag{
+			{
+				Name:     "scope",
+				Short:    "s",
+				Usage:    `--scope ./...`,
+				Help:     `Comma-separated list of files and directories to change. A directory ending in /... includes its subdirectories. Default is ./...`,
+				Variable: true,
+			},
+			{
+				Name:  "dry-run",
+				Short: "n",
+				Usage: `--dry-run`,
+				Help:  `List the files that would change without changing them`,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) != 2 {
+				fmt.Fprint(stderr, localise(msgRenameNamesRequired))
+				return 1
+			}
+
+			oldName, newName := ctx.Args[0], ctx.Args[1]
+			scope := []string{"./..."}
+
+			if s, set := ctx.Get("scope"); set {
+				scope = strings.Split(s, ",")
+			}
+
+			files, err := sourceFiles(scope)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgListFiles, err.Error()))
+				return 1
+			}
+
+			// Every file is renamed before any are written, so that a file
+			// which can't be parsed doesn't leave the rename half done
+			var changes []sourceChange
+			total := 0
+
+			for _, fileName := range files {
+
+				src, err := ioutil.ReadFile(fileName)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgReadFile, err.Error()))
+					return 1
+				}
+
+				out, renamed, err := scl.RenameMixin(src, fileName, oldName, newName)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgRename, err.Error()))
+					return 1
+				}
+
+				if renamed > 0 {
+					changes = append(changes, sourceChange{fileName, out})
+					total += renamed
+
+					fmt.Fprint(stdout, localise(msgRenamed, fileName, renamed))
+				}
+			}
+
+			if !ctx.Is("dry-run") {
+				for _, c := range changes {
+					if err := writeSourceFile(c.fileName, c.content); err != nil {
+						fmt.Fprint(stderr, localise(msgWriteFile, err.Error()))
+						return 1
+					}
+				}
+			}
+
+			if total == 0 {
+				fmt.Fprint(stderr, localise(msgNoReferences, oldName))
+				return 1
+			}
+
+			return 0
+		},
+	}
+}
+
+// A sourceChange is the new content of a file changed by a refactoring.
+type sourceChange struct {
+	fileName string
+	content  []byte
+}
+
+// writeSourceFile replaces the content of a file atomically, keeping its
+// permissions.
+func writeSourceFile(fileName string, content []byte) error {
+
+	mode := os.FileMode(0644)
+
+	if stat, err := os.Stat(fileName); err == nil {
+		mode = stat.Mode()
+	}
+
+	return writeFileAtomically(fileName, content, mode)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A retryPolicy runs an operation until it succeeds or runs out of attempts,
+// doubling the wait between each attempt.
+type retryPolicy struct {
+	retries int
+	backoff time.Duration
+	timeout time.Duration
+}
+
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.timeout)
+}
+
+/*
+do runs fn, retrying it if it fails. The retry callback, if given, is called
+before each retry with the error that caused it.
+
+Each attempt is given a context which is cancelled when the attempt times out.
+Operations must stop when their context is done, for example by running
+commands with exec.CommandContext, so that a timed-out attempt is no longer
+running when the next one starts.
+*/
+func (r retryPolicy) do(fn func(ctx context.Context) error, retry func(attempt int, err error)) (err error) {
+
+	wait := r.backoff
+
+	for attempt := 0; ; attempt++ {
+
+		if err = r.withTimeout(fn); err == nil || attempt >= r.retries {
+			return err
+		}
+
+		if retry != nil {
+			retry(attempt+1, err)
+		}
+
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// withTimeout runs fn with a context that's cancelled after the timeout, and
+// waits for it to return.
+func (r retryPolicy) withTimeout(fn func(ctx context.Context) error) error {
+
+	if r.timeout <= 0 {
+		return fn(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	err := fn(ctx)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return timeoutError{r.timeout}
+	}
+
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func searchCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "search",
+		Brief: "Search the mixins of every library for a term",
+		Usage: `[options] <term>`,
+		Help:  "Search the names, parameters and documentation of the mixins in every library in the vendor directory and include paths, and the metadata of the files they're declared in, and print the matching definitions. Matches are case-insensitive, and those in names are listed first.",
+
+		Flags: standardParserParams(),
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) != 1 {
+				fmt.Fprint(stderr, localise(msgSearchTermRequired))
+				return 1
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgCreateParser, err.Error()))
+				return 1
+			}
+
+			index, err := buildLibraryIndex(parser, libraryRoots(includePaths))
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgIndexLibraries, err.Error()))
+				return 1
+			}
+
+			matches := index.search(ctx.Args[0])
+
+			for _, m := range matches {
+
+				fmt.Fprintf(stdout, "%s\t%s\n", m.doc.Reference, m.doc.Signature)
+
+				if summary := docSummary(m.doc.Docs); summary != "" {
+					fmt.Fprintf(stdout, "\t%s\n", summary)
+				}
+			}
+
+			if len(matches) == 0 {
+				fmt.Fprint(stderr, localise(msgNoMatchingMixins, ctx.Args[0]))
+				return 1
+			}
+
+			return 0
+		},
+	}
+}
+
+// A searchField is where a search term was found in a mixin. Fields are
+// ranked in the order declared.
+type searchField int
+
+const (
+	searchName searchField = iota
+	searchParams
+	searchDocs
+	searchMetadata
+)
+
+type searchMatch struct {
+	doc   scl.MixinDoc
+	field searchField
+}
+
+// search finds the mixins whose name, parameters or documentation contain the
+// term, or which are declared in a file whose description, author or tags do,
+// best matches first.
+func (index *libraryIndex) search(term string) (matches []searchMatch) {
+
+	term = strings.ToLower(term)
+
+	index.walkMixins(func(_ *indexedLibrary, f indexedFile, d scl.MixinDoc) {
+
+		params := strings.TrimPrefix(d.Signature, "@"+d.Name)
+		metadata := strings.Join(append([]string{f.Metadata.Description, f.Metadata.Author}, f.Metadata.Tags...), "\n")
+
+		switch {
+		case strings.Contains(strings.ToLower(d.Name), term):
+			matches = append(matches, searchMatch{d, searchName})
+		case strings.Contains(strings.ToLower(params), term):
+			matches = append(matches, searchMatch{d, searchParams})
+		case strings.Contains(strings.ToLower(d.Docs), term):
+			matches = append(matches, searchMatch{d, searchDocs})
+		case strings.Contains(strings.ToLower(metadata), term):
+			matches = append(matches, searchMatch{d, searchMetadata})
+		}
+	})
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].field < matches[j].field
+	})
+
+	return
+}
+
+// docSummary returns the first non-blank line of a mixin's documentation.
+func docSummary(docs string) string {
+
+	for _, line := range strings.Split(docs, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
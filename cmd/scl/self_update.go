@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/tucnak/climax"
+)
+
+const (
+	defaultReleaseEndpoint = "https://api.github.com/repos/homemade/scl/releases/latest"
+	releaseChecksumsName   = "checksums.txt"
+	releaseSignatureName   = "checksums.txt.sig"
+)
+
+/*
+releasePublicKey is the hex-encoded ed25519 public key that release checksums
+are signed with. It's set by the linker when release binaries are built:
+
+	go build -ldflags "-X main.releasePublicKey=$(cat release.pub)"
+
+The checksums file of each release is signed with the matching private key,
+and the detached signature is published as checksums.txt.sig, either as the
+raw 64 bytes or base64-encoded. A checksums file fetched from the same place
+as the binary only shows that the download wasn't corrupted; the signature
+shows that it's the release that was published. Builds without a key can't
+update themselves.
+*/
+var releasePublicKey = ""
+
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+func selfUpdateCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "self-update",
+		Brief: "Replace scl with the latest release",
+		Usage: `[options]`,
+		Help:  "Check for a newer release of scl and, if there is one, download the binary for this platform, verify it against the release checksums, whose signature is checked against the release key built into scl, and replace the running binary with it.",
+
+		Flags: []climax.Flag{
+			{
+				Name:  "check",
+				Usage: `--check`,
+				Help:  `Only report whether a newer release is available`,
+			},
+			{
+				Name:     "endpoint",
+				Usage:    `--endpoint https://api.github.com/repos/homemade/scl/releases/latest`,
+				Help:     `The release endpoint, which must respond like the GitHub releases API`,
+				Variable: true,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			endpoint := defaultReleaseEndpoint
+
+			if e, set := ctx.Get("endpoint"); set {
+				endpoint = e
+			}
+
+			release, err := latestRelease(endpoint)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgCheckReleases, err.Error()))
+				return 1
+			}
+
+			current, _ := parseSemver(version)
+			latest, ok := parseSemver(release.Tag)
+
+			if !ok {
+				fmt.Fprint(stderr, localise(msgInvalidRelease, release.Tag))
+				return 1
+			}
+
+			if !current.less(latest) {
+				fmt.Fprint(stdout, localise(msgSCLUpToDate, version))
+				return 0
+			}
+
+			if ctx.Is("check") {
+				fmt.Fprint(stdout, localise(msgSCLAvailable, release.Tag, version))
+				return 0
+			}
+
+			executable, err := os.Executable()
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgFindBinary, err.Error()))
+				return 1
+			}
+
+			if executable, err = filepath.EvalSymlinks(executable); err != nil {
+				fmt.Fprint(stderr, localise(msgFindBinary, err.Error()))
+				return 1
+			}
+
+			if err := release.install(executable); err != nil {
+				fmt.Fprint(stderr, localise(msgUpdateSCL, err.Error()))
+				return 1
+			}
+
+			fmt.Fprint(stdout, localise(msgSCLUpdated, version, release.Tag))
+
+			return 0
+		},
+	}
+}
+
+type release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func latestRelease(endpoint string) (r release, err error) {
+
+	resp, err := releaseClient.Get(endpoint)
+
+	if err != nil {
+		return r, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return r, fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&r)
+
+	return r, err
+}
+
+// assetName is the name of the release binary for this platform, for
+// example scl_1.4.0_linux_amd64.
+func (r release) assetName() string {
+
+	name := fmt.Sprintf("scl_%s_%s_%s", strings.TrimPrefix(r.Tag, "v"), runtime.GOOS, runtime.GOARCH)
+
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	return name
+}
+
+func (r release) assetURL(name string) (string, error) {
+
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, nil
+		}
+	}
+
+	return "", fmt.Errorf("release %s has no %s", r.Tag, name)
+}
+
+/*
+install downloads the binary for this platform next to the executable,
+verifies it against the release's signed checksums file and then moves it into
+place. The old binary is renamed rather than overwritten, because a running
+binary can't be replaced on some platforms, and is removed afterwards if
+possible.
+*/
+func (r release) install(executable string) error {
+
+	name := r.assetName()
+
+	checksums, err := r.checksums()
+
+	if err != nil {
+		return err
+	}
+
+	expected, ok := checksums[name]
+
+	if !ok {
+		return fmt.Errorf("%s has no checksum for %s", releaseChecksumsName, name)
+	}
+
+	url, err := r.assetURL(name)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(executable), ".scl-update-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+
+	if err := download(url, io.MultiWriter(tmp, hash)); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s (expected %s, got %s)", name, expected, actual)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	old := executable + ".old"
+
+	if err := os.Rename(executable, old); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		os.Rename(old, executable)
+		return err
+	}
+
+	os.Remove(old)
+
+	return nil
+}
+
+// checksums reads the release's checksums file, which is in the format
+// written by sha256sum, after checking its signature.
+func (r release) checksums() (map[string]string, error) {
+
+	content, err := r.downloadAsset(releaseChecksumsName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := r.downloadAsset(releaseSignatureName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyReleaseSignature(releasePublicKey, content, signature); err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+
+	return checksums, scanner.Err()
+}
+
+func (r release) downloadAsset(name string) ([]byte, error) {
+
+	url, err := r.assetURL(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if err := download(url, &buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// verifyReleaseSignature checks a detached ed25519 signature of a release's
+// checksums file against the hex-encoded public key.
+func verifyReleaseSignature(publicKey string, content, signature []byte) error {
+
+	if publicKey == "" {
+		return fmt.Errorf("this build of scl has no release key to verify updates with")
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(publicKey))
+
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("this build of scl has an invalid release key")
+	}
+
+	if len(signature) != ed25519.SignatureSize {
+		if signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err != nil {
+			return fmt.Errorf("%s is neither a raw nor a base64-encoded signature", releaseSignatureName)
+		}
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(key), content, signature) {
+		return fmt.Errorf("the signature of %s doesn't match the release key", releaseChecksumsName)
+	}
+
+	return nil
+}
+
+func download(url string, w io.Writer) error {
+
+	resp, err := releaseClient.Get(url)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
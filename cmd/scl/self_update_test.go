@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReleaseChecksumsMustBeSigned(t *testing.T) {
+
+	public, private, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+
+	_, other, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+
+	checksums := []byte("abc123  scl_1.4.0_linux_amd64\n")
+	signature := ed25519.Sign(private, checksums)
+
+	for cycle, input := range []struct {
+		key       string
+		content   []byte
+		signature []byte
+		err       error
+	}{
+		{
+			key:       hex.EncodeToString(public),
+			content:   checksums,
+			signature: signature,
+		},
+		{
+			key:       hex.EncodeToString(public),
+			content:   checksums,
+			signature: []byte(base64.StdEncoding.EncodeToString(signature) + "\n"),
+		},
+		{
+			key:       hex.EncodeToString(public),
+			content:   []byte("def456  scl_1.4.0_linux_amd64\n"),
+			signature: signature,
+			err:       fmt.Errorf("the signature of checksums.txt doesn't match the release key"),
+		},
+		{
+			key:       hex.EncodeToString(public),
+			content:   checksums,
+			signature: ed25519.Sign(other, checksums),
+			err:       fmt.Errorf("the signature of checksums.txt doesn't match the release key"),
+		},
+		{
+			key:       "",
+			content:   checksums,
+			signature: signature,
+			err:       fmt.Errorf("this build of scl has no release key to verify updates with"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.err, verifyReleaseSignature(input.key, input.content, input.signature))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver is a minimal semantic version, as used in library tags
+// like v1.2.3. Pre-release suffixes are compared by semver precedence; build
+// suffixes are ignored.
+type semver struct {
+	major, minor, patch int
+	pre                 string
+}
+
+func parseSemver(tag string) (v semver, ok bool) {
+
+	s := strings.TrimPrefix(tag, "v")
+
+	if i := strings.IndexAny(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.pre = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+
+	for i, part := range parts {
+
+		n, err := strconv.Atoi(part)
+
+		if err != nil || n < 0 {
+			return v, false
+		}
+
+		*numbers[i] = n
+	}
+
+	return v, true
+}
+
+func (v semver) less(o semver) bool {
+
+	switch {
+	case v.major != o.major:
+		return v.major < o.major
+	case v.minor != o.minor:
+		return v.minor < o.minor
+	case v.patch != o.patch:
+		return v.patch < o.patch
+	}
+
+	// A pre-release sorts before its release
+	if v.pre == "" || o.pre == "" {
+		return v.pre != "" && o.pre == ""
+	}
+
+	return preReleaseLess(v.pre, o.pre)
+}
+
+// preReleaseLess compares pre-release suffixes identifier by identifier.
+// Numeric identifiers are compared as numbers and sort before alphanumeric
+// ones, and a suffix that runs out first sorts first: rc.1 < rc.2 < rc.10 and
+// alpha < alpha.1 < beta.
+func preReleaseLess(a, b string) bool {
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+
+		if as[i] == bs[i] {
+			continue
+		}
+
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			return an < bn
+		case aErr == nil:
+			return true
+		case bErr == nil:
+			return false
+		}
+
+		return as[i] < bs[i]
+	}
+
+	return len(as) < len(bs)
+}
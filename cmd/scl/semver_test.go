@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ASemanticVersionCanBeParsed(t *testing.T) {
+
+	for cycle, input := range []struct {
+		tag      string
+		expected semver
+		ok       bool
+	}{
+		{"v1.2.3", semver{major: 1, minor: 2, patch: 3}, true},
+		{"1.2", semver{major: 1, minor: 2}, true},
+		{"v2", semver{major: 2}, true},
+		{"v1.0.0-rc.1", semver{major: 1, pre: "rc.1"}, true},
+		{"v1.0.0-rc1+build.5", semver{major: 1, pre: "rc1"}, true},
+		{"v1.0.0+build.5", semver{major: 1}, true},
+		{"v1.2.3.4", semver{}, false},
+		{"v1.x", semver{}, false},
+		{"latest", semver{}, false},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		v, ok := parseSemver(input.tag)
+
+		require.Equal(t, input.ok, ok)
+
+		if ok {
+			require.Equal(t, input.expected, v)
+		}
+	}
+}
+
+func Test_SemanticVersionsAreOrderedByPrecedence(t *testing.T) {
+
+	for cycle, input := range []struct {
+		lower, higher string
+	}{
+		{"v1.0.0", "v2.0.0"},
+		{"v1.1.0", "v1.2.0"},
+		{"v1.2.3", "v1.2.4"},
+		{"v1.9.0", "v1.10.0"},
+		{"v1.0.0-rc1", "v1.0.0"},
+		{"v1.0.0-rc1", "v1.0.0-rc2"},
+		{"v1.0.0-rc.2", "v1.0.0-rc.10"},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1"},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta"},
+		{"v1.0.0-alpha.beta", "v1.0.0-beta"},
+		{"v1.0.0-1", "v1.0.0-alpha"},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		lower, ok := parseSemver(input.lower)
+		require.True(t, ok)
+
+		higher, ok := parseSemver(input.higher)
+		require.True(t, ok)
+
+		require.True(t, lower.less(higher))
+		require.False(t, higher.less(lower))
+		require.False(t, lower.less(lower))
+	}
+
+	// Build suffixes don't affect precedence
+	a, _ := parseSemver("v1.0.0+a")
+	b, _ := parseSemver("v1.0.0+b")
+	require.False(t, a.less(b))
+	require.False(t, b.less(a))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+/*
+isSOPSEncrypted reports whether a file's content has been encrypted by SOPS.
+SCL files aren't JSON or YAML, so SOPS encrypts them in its binary format: a
+JSON object holding the encrypted data and a "sops" metadata key.
+*/
+func isSOPSEncrypted(content []byte) bool {
+
+	trimmed := bytes.TrimSpace(content)
+
+	if len(trimmed) == 0 || trimmed[0] != '{' || !bytes.Contains(trimmed, []byte(`"sops"`)) {
+		return false
+	}
+
+	var document map[string]json.RawMessage
+
+	if err := json.Unmarshal(trimmed, &document); err != nil {
+		return false
+	}
+
+	_, hasMetadata := document["sops"]
+	_, hasData := document["data"]
+
+	return hasMetadata && hasData
+}
+
+/*
+decryptSOPS is a preprocessor which decrypts SOPS-encrypted files by running
+the sops binary, leaving any other file untouched. The keys are found by sops
+itself, from its usual environment variables and key files, so nothing about
+them is configured here. The binary is looked up on the PATH, unless the SOPS
+environment variable names another one.
+*/
+func decryptSOPS(path string, content []byte) ([]byte, error) {
+
+	if !isSOPSEncrypted(content) {
+		return content, nil
+	}
+
+	binary, err := exec.LookPath(sopsBinary())
+
+	if err != nil {
+		return nil, fmt.Errorf("%s is encrypted with SOPS, but the sops binary can't be found", path)
+	}
+
+	// The file may not be on disk under this name, if it's virtual or
+	// preprocessed, so sops is given a copy of the content
+	tmp, err := ioutil.TempFile("", "scl-sops-")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(binary, "--decrypt", "--input-type", "binary", "--output-type", "binary", tmp.Name())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sops: %s", msg)
+		}
+
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// sopsBinary is the sops binary to run, which can be overridden with the SOPS
+// environment variable.
+func sopsBinary() string {
+
+	if binary := os.Getenv("SOPS"); binary != "" {
+		return binary
+	}
+
+	return "sops"
+}
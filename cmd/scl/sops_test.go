@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SOPSFilesAreDecrypted(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "scl-sops-test-")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	stub := func(name, script string) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+		return path
+	}
+
+	decrypting := stub("decrypting", `[ "$1" = "--decrypt" ] || exit 2; echo 'a = 1'`)
+	failing := stub("failing", `echo 'no key could decrypt the data' >&2; exit 1`)
+
+	encrypted := []byte(`{"data": "ENC[AES256_GCM,data:abc]", "sops": {"version": "3.7.3"}}`)
+
+	defer os.Setenv("SOPS", os.Getenv("SOPS"))
+
+	for cycle, input := range []struct {
+		binary  string
+		content []byte
+		output  []byte
+		err     error
+	}{
+		{
+			binary:  decrypting,
+			content: encrypted,
+			output:  []byte("a = 1\n"),
+		},
+		{
+			binary:  failing,
+			content: encrypted,
+			err:     fmt.Errorf("sops: no key could decrypt the data"),
+		},
+		{
+			binary:  filepath.Join(dir, "missing"),
+			content: encrypted,
+			err:     fmt.Errorf("secrets.scl is encrypted with SOPS, but the sops binary can't be found"),
+		},
+		{
+			binary:  failing,
+			content: []byte("a = 1\n"),
+			output:  []byte("a = 1\n"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		require.Nil(t, os.Setenv("SOPS", input.binary))
+
+		output, err := decryptSOPS("secrets.scl", input.content)
+
+		require.Equal(t, input.err, err)
+		require.Equal(t, string(input.output), string(output))
+	}
+}
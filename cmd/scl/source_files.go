@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/homemade/scl"
+)
+
+/*
+sourceFiles expands patterns into the .scl files they name, in the same way
+that the go tool expands package patterns:
+
+	file.scl      the file itself
+	dir           the .scl files in the directory
+	dir/...       the .scl files in the directory and all its subdirectories
+
+Recursive patterns skip vendor directories and hidden directories, since
+vendored libraries aren't the project's to change.
+*/
+func sourceFiles(patterns []string) ([]string, error) {
+
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+
+		recursive := pattern == "..." || strings.HasSuffix(pattern, "/...")
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+
+		if root == "" {
+			root = "."
+		}
+
+		stat, err := os.Stat(root)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !stat.IsDir() {
+			seen[root] = true
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+
+				if path == root {
+					return nil
+				}
+
+				if !recursive || info.Name() == "vendor" || strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if filepath.Ext(path) == ".scl" {
+				seen[path] = true
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+
+	for path := range seen {
+		files = append(files, path)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// globFiles expands the wildcards in file globs, as a shell would, so that a
+// quoted glob such as '**/*.scl' can use ** and skip the paths in .sclignore,
+// and vendor and node_modules directories. Names without wildcards are kept
+// as they are, even if they're ignored, but a glob that matches no files is an
+// error. The files are listed in the order of the globs, without duplicates.
+func globFiles(globs []string) (files []string, err error) {
+
+	fs := scl.NewDiskSystem()
+	seen := make(map[string]bool)
+
+	for _, glob := range globs {
+
+		matches := []string{glob}
+
+		if strings.ContainsAny(glob, "*?[") {
+
+			if matches, err = fs.Glob(glob); err != nil {
+				return nil, err
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", glob)
+			}
+
+			sort.Strings(matches)
+		}
+
+		for _, match := range matches {
+
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
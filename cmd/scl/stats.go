@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tucnak/climax"
+)
+
+/*
+Usage stats are only recorded if SCL_STATS is set to 1, or SCL_STATS_FILE is
+set to the file they should be written to. They are kept in a local file, one
+JSON record per line, and are never sent anywhere.
+*/
+const (
+	statsEnabledVariable = "SCL_STATS"
+	statsFileVariable    = "SCL_STATS_FILE"
+)
+
+type statsRecord struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Files      int       `json:"files"`
+	InputBytes int64     `json:"input_bytes"`
+	Duration   float64   `json:"duration_seconds"`
+	ExitCode   int       `json:"exit_code"`
+}
+
+// statsFile returns the path of the stats file, and whether stats should be
+// recorded at all.
+func statsFile() (string, bool) {
+
+	if path := os.Getenv(statsFileVariable); path != "" {
+		return path, true
+	}
+
+	if os.Getenv(statsEnabledVariable) != "1" {
+		return "", false
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".scl", "stats.jsonl"), true
+}
+
+// withStats wraps a command so that its duration and the size of the files
+// it was given are recorded, if stats are enabled. Failing to record them
+// never affects the command.
+func withStats(cmd climax.Command) climax.Command {
+
+	handle := cmd.Handle
+
+	cmd.Handle = func(ctx climax.Context) int {
+
+		path, enabled := statsFile()
+
+		if !enabled {
+			return handle(ctx)
+		}
+
+		start := time.Now()
+		code := handle(ctx)
+
+		record := statsRecord{
+			Time:     start.UTC(),
+			Command:  cmd.Name,
+			Duration: time.Since(start).Seconds(),
+			ExitCode: code,
+		}
+
+		for _, arg := range ctx.Args {
+			if stat, err := os.Stat(arg); err == nil && !stat.IsDir() {
+				record.Files++
+				record.InputBytes += stat.Size()
+			}
+		}
+
+		appendStats(path, record)
+
+		return code
+	}
+
+	return cmd
+}
+
+func appendStats(path string, record statsRecord) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(record)
+}
+
+func loadStats(path string) (records []statsRecord, err error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+
+		var r statsRecord
+
+		// Skip lines that were only partly written
+		if err := json.Unmarshal(scanner.Bytes(), &r); err == nil {
+			records = append(records, r)
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+func statsCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "stats",
+		Brief: "Summarise locally recorded usage stats",
+		Usage: `[options]`,
+		Help:  "Summarise the durations and input sizes of scl commands run on this machine. Stats are only recorded if SCL_STATS=1 or SCL_STATS_FILE is set, and are never sent anywhere.",
+
+		Flags: []climax.Flag{
+			{
+				Name:     "since",
+				Usage:    `--since 168h`,
+				Help:     `Only include commands run within this duration`,
+				Variable: true,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			path, enabled := statsFile()
+
+			if !enabled {
+				fmt.Fprint(stdout, localise(msgStatsDisabled, statsEnabledVariable, statsFileVariable))
+				return 0
+			}
+
+			records, err := loadStats(path)
+
+			if os.IsNotExist(err) {
+				fmt.Fprint(stdout, localise(msgNoStats, path))
+				return 0
+			} else if err != nil {
+				fmt.Fprint(stderr, localise(msgReadStats, err.Error()))
+				return 1
+			}
+
+			var since time.Time
+
+			if s, set := ctx.Get("since"); set {
+
+				d, err := time.ParseDuration(s)
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgInvalidDuration, err.Error()))
+					return 1
+				}
+
+				since = time.Now().Add(-d)
+			}
+
+			writeStatsSummary(stdout, records, since)
+
+			return 0
+		},
+	}
+}
+
+type statsSummary struct {
+	command    string
+	runs       int
+	failures   int
+	total      float64
+	slowest    float64
+	inputBytes int64
+}
+
+func writeStatsSummary(w io.Writer, records []statsRecord, since time.Time) {
+
+	summaries := make(map[string]*statsSummary)
+
+	for _, r := range records {
+
+		if r.Time.Before(since) {
+			continue
+		}
+
+		s, ok := summaries[r.Command]
+
+		if !ok {
+			s = &statsSummary{command: r.Command}
+			summaries[r.Command] = s
+		}
+
+		s.runs++
+		s.total += r.Duration
+		s.inputBytes += r.InputBytes
+
+		if r.ExitCode != 0 {
+			s.failures++
+		}
+
+		if r.Duration > s.slowest {
+			s.slowest = r.Duration
+		}
+	}
+
+	if len(summaries) == 0 {
+		fmt.Fprint(w, localise(msgNoCommandsRecorded))
+		return
+	}
+
+	sorted := make([]*statsSummary, 0, len(summaries))
+
+	for _, s := range summaries {
+		sorted = append(sorted, s)
+	}
+
+	// The commands that take up the most time overall come first
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].total > sorted[j].total
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "COMMAND\tRUNS\tFAILED\tTOTAL\tMEAN\tSLOWEST\tMEAN INPUT")
+
+	for _, s := range sorted {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2fs\t%.3fs\t%.3fs\t%d bytes\n",
+			s.command, s.runs, s.failures, s.total, s.total/float64(s.runs), s.slowest, s.inputBytes/int64(s.runs))
+	}
+
+	tw.Flush()
+}
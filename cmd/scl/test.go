@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aryann/difflib"
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+func testCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "test",
+		Brief: "Parse each .scl file in a directory and compare the output to an .hcl file",
+		Usage: `[options] [file-glob...]`,
+		Help:  "Parse each .scl file in a directory and compare the output to an .hcl file, a .json file, or both. A .json file is compared with the output rendered as JSON, as by `scl run --json`. Quote a glob such as '**/*.scl' to search every subdirectory, skipping the paths listed in a .sclignore file and vendor and node_modules directories.",
+
+		Flags: append(append(standardParserParams(), outputFormatParams()...),
+			climax.Flag{
+				Name:     "run",
+				Usage:    `--run <regexp>`,
+				Help:     `Only run test files whose names match the regular expression`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "tags",
+				Usage:    `--tags slow,!network`,
+				Help:     `Comma-separated list of tags declared with "// scl:tags" comments. Only files with one of the tags are run, and files with a tag prefixed by ! are skipped`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "ignore-whitespace",
+				Usage: `--ignore-whitespace`,
+				Help:  `Ignore differences in indentation and spacing within lines`,
+			},
+			climax.Flag{
+				Name:  "ignore-blank-lines",
+				Usage: `--ignore-blank-lines`,
+				Help:  `Ignore blank lines in both the expected and actual output`,
+			},
+			climax.Flag{
+				Name:  "ignore-key-order",
+				Usage: `--ignore-key-order`,
+				Help:  `Decode the expected and actual output as HCL and compare the results, so the order of keys doesn't matter`,
+			},
+			climax.Flag{
+				Name:  "semantic",
+				Usage: `--semantic`,
+				Help:  `Compare the expected and actual output as HCL structures, ignoring formatting and the order of keys and blocks`,
+			},
+			climax.Flag{
+				Name:  "coverage",
+				Usage: `--coverage`,
+				Help:  `Report which mixins and includes outside the test files were used by the tests. Every .scl file in the vendor directory and include paths is counted, including those no test reached`,
+			},
+			climax.Flag{
+				Name:     "coverage-html",
+				Usage:    `--coverage-html <file>`,
+				Help:     `Write an HTML coverage report to the given file. Implies --coverage`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "fail-fast",
+				Usage: `--fail-fast`,
+				Help:  `Stop after the first failing test. The same as --max-failures 1`,
+			},
+			climax.Flag{
+				Name:     "max-failures",
+				Usage:    `--max-failures <n>`,
+				Help:     `Stop after n tests have failed. By default every test is run`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "retries",
+				Usage:    `--retries <n>`,
+				Help:     `Run failing tests up to n more times, for tests that depend on remote filesystems or providers. Tests that only pass on a retry are listed at the end`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "timing",
+				Usage: `--timing`,
+				Help:  `List the slowest tests and the total time taken by the tests in each directory`,
+			},
+			climax.Flag{
+				Name:     "timing-count",
+				Usage:    `--timing-count <n>`,
+				Help:     `The number of tests listed by --timing. Default is 10`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "changed-since",
+				Usage:    `--changed-since <git-ref>`,
+				Help:     `Only run tests whose file, expected output or includes have changed since the git ref, including uncommitted changes`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:  "mutate",
+				Usage: `--mutate`,
+				Help:  `Re-run each passing test with its parameters perturbed in turn, and fail if the output still matches. The parameters are those named in "// scl:mutate" comments, or every --param if there are none`,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			errors := 0
+
+			if len(ctx.Args) == 0 {
+				fmt.Fprint(stderr, localise(msgGlobRequired))
+				return 1
+			}
+
+			fileNames, err := globFiles(ctx.Args)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgListFiles, err.Error()))
+				return 1
+			}
+
+			run, _ := ctx.Get("run")
+			tags, _ := ctx.Get("tags")
+			filter, err := newTestFilter(run, tags)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgInvalidRunExpression, err.Error()))
+				return 1
+			}
+
+			maxFailures := 0
+
+			if max, set := ctx.Get("max-failures"); set {
+				if maxFailures, err = strconv.Atoi(max); err != nil || maxFailures < 1 {
+					fmt.Fprint(stderr, localise(msgInvalidFailures, max))
+					return 1
+				}
+			}
+
+			if ctx.Is("fail-fast") {
+				maxFailures = 1
+			}
+
+			retries := 0
+
+			if r, set := ctx.Get("retries"); set {
+				if retries, err = strconv.Atoi(r); err != nil || retries < 0 {
+					fmt.Fprint(stderr, localise(msgInvalidRetries, r))
+					return 1
+				}
+			}
+
+			slowest := defaultSlowestTests
+
+			if n, set := ctx.Get("timing-count"); set {
+				if slowest, err = strconv.Atoi(n); err != nil || slowest < 1 {
+					fmt.Fprint(stderr, localise(msgInvalidTests, n))
+					return 1
+				}
+			}
+
+			format, err := outputFormat(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			comparison := outputComparison{
+				ignoreWhitespace: ctx.Is("ignore-whitespace"),
+				ignoreBlankLines: ctx.Is("ignore-blank-lines"),
+				ignoreKeyOrder:   ctx.Is("ignore-key-order"),
+				semantic:         ctx.Is("semantic"),
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			runner := testRunner{
+				params:       params,
+				environment:  environment,
+				includePaths: includePaths,
+				workspace:    workspace,
+				ignoreCase:   caseInsensitive,
+				comparison:   comparison,
+				format:       format,
+			}
+
+			var mutations []string
+
+			if ctx.Is("mutate") {
+				if ps, set := ctx.Get("param"); set {
+					for _, p := range strings.Split(ps, ",") {
+						if name := strings.TrimSpace(strings.SplitN(p, "=", 2)[0]); name != "" {
+							mutations = append(mutations, name)
+						}
+					}
+				}
+			}
+
+			var changed map[string]bool
+
+			changedSince, filterChanged := ctx.Get("changed-since")
+
+			if filterChanged {
+				if changed, err = changedFiles(changedSince); err != nil {
+					fmt.Fprint(stderr, localise(msgListChangedFiles, err.Error()))
+					return 1
+				}
+			}
+
+			unaffected := 0
+
+			coverageHTML, _ := ctx.Get("coverage-html")
+			coverage := newCoverageReport()
+
+			if ctx.Is("coverage") || coverageHTML != "" {
+
+				parser, err := runner.parser()
+
+				if err == nil {
+					err = coverage.seed(parser, libraryRoots(includePaths), fileNames)
+				}
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgListCoverageStatements, err.Error()))
+					return 1
+				}
+			}
+			timing := newTimingReport()
+
+			output := newSyncOutput(stdout, stderr)
+
+			var (
+				flaky        []string
+				summaryMutex sync.Mutex
+			)
+
+			testFile := func(fileName string, out *fileOutput) {
+
+				tc, err := loadTestCase(fileName)
+
+				if err != nil {
+					out.fail(fileName, "%s", localise(msgFailReadFile, err.Error()))
+					return
+				}
+
+				if !filter.matches(tc) {
+					return
+				}
+
+				if filterChanged && !runner.affected(tc, changed) {
+
+					summaryMutex.Lock()
+					unaffected++
+					summaryMutex.Unlock()
+
+					return
+				}
+
+				result := runner.runWithRetries(tc, retries)
+				coverage.add(tc, result.coverage)
+				timing.add(fileName, result.duration)
+
+				switch result.status {
+
+				case testPassed:
+
+					if ctx.Is("mutate") {
+						if survivors := runner.mutate(tc, mutations); len(survivors) > 0 {
+							out.fail(fileName, "%s", localise(msgFailMutation, strings.Join(survivors, ", ")))
+							return
+						}
+					}
+
+					if result.attempts > 1 {
+
+						fmt.Fprintf(&out.stdout, "%-7s %s\t%.3fs (passed on attempt %d)\n", "ok", fileName, result.duration.Seconds(), result.attempts)
+
+						summaryMutex.Lock()
+						flaky = append(flaky, fileName)
+						summaryMutex.Unlock()
+
+						return
+					}
+
+					fmt.Fprintf(&out.stdout, "%-7s %s\t%.3fs\n", "ok", fileName, result.duration.Seconds())
+
+				case testSkipped:
+					fmt.Fprintf(&out.stdout, "%-7s %s [%s]\n", "?", fileName, result.message)
+
+				case testFailed:
+					out.fail(fileName, "%s", result.message)
+
+					if len(result.diff) > 0 {
+
+						fmt.Fprintln(&out.stderr)
+
+						for _, d := range result.diff {
+							fmt.Fprintf(&out.stderr, "\t%s\n", d.String())
+						}
+
+						fmt.Fprintln(&out.stderr)
+					}
+				}
+			}
+
+			for i, fileName := range fileNames {
+
+				out := &fileOutput{}
+				testFile(fileName, out)
+				output.flush(out)
+
+				errors += out.failures
+
+				if maxFailures > 0 && errors >= maxFailures {
+					if remaining := len(fileNames) - i - 1; remaining > 0 {
+						fmt.Fprint(stderr, localise(msgStoppedAfter, errors, remaining))
+					}
+					break
+				}
+			}
+
+			if unaffected > 0 {
+				fmt.Fprint(stdout, localise(msgNotRunUnchanged, unaffected, changedSince))
+			}
+
+			if len(flaky) > 0 {
+
+				fmt.Fprint(stdout, localise(msgFlaky, len(flaky)))
+
+				for _, fileName := range flaky {
+					fmt.Fprintf(stdout, "\t%s\n", fileName)
+				}
+			}
+
+			if ctx.Is("timing") {
+				timing.write(stdout, slowest)
+			}
+
+			if ctx.Is("coverage") || coverageHTML != "" {
+				coverage.writeSummary(stdout)
+			}
+
+			if coverageHTML != "" {
+				if err := coverage.writeHTML(coverageHTML); err != nil {
+					fmt.Fprint(stderr, localise(msgWriteCoverage, err.Error()))
+					errors++
+				}
+			}
+
+			if errors > 0 {
+				fmt.Fprint(stderr, localise(msgFailedErrors, errors))
+				return 1
+			}
+
+			return 0
+		},
+	}
+}
+
+type testStatus int
+
+const (
+	testPassed testStatus = iota
+	testFailed
+	testSkipped
+)
+
+type testResult struct {
+	status   testStatus
+	message  string
+	diff     []difflib.DiffRecord
+	duration time.Duration
+	coverage scl.CoverageBlocks
+	attempts int
+}
+
+// A testRunner parses test cases and compares their output with the expected
+// output in the .hcl and .json files alongside them.
+type testRunner struct {
+	params       paramSlice
+	environment  scl.Environment
+	includePaths []string
+	workspace    scl.Workspace
+	ignoreCase   bool
+	comparison   outputComparison
+	format       scl.OutputFormat
+}
+
+func (r testRunner) run(tc testCase) testResult {
+
+	now := time.Now()
+
+	var coverage scl.CoverageBlocks
+
+	failed := func(diff []difflib.DiffRecord, message string) testResult {
+		return testResult{status: testFailed, message: message, diff: diff, duration: time.Since(now), coverage: coverage}
+	}
+
+	parser, err := r.parser()
+
+	if err != nil {
+		return failed(nil, localise(msgFailCreateParser, err.Error()))
+	}
+
+	if err := parser.Parse(tc.fileName); err != nil {
+		return failed(nil, localise(msgFailParseFile, err.Error()))
+	}
+
+	coverage = parser.Coverage()
+
+	expectations := 0
+	base := strings.TrimSuffix(tc.fileName, ".scl")
+
+	for _, expectation := range []struct {
+		extension string
+		compare   func(expected, actual string) (bool, []difflib.DiffRecord, error)
+	}{
+		{".hcl", r.comparison.compare},
+		{".json", compareJSON},
+	} {
+
+		expected, err := ioutil.ReadFile(base + expectation.extension)
+
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return failed(nil, localise(msgFailReadExpected, expectation.extension, err.Error()))
+		}
+
+		expectations++
+
+		success, diff, err := expectation.compare(string(expected), parser.String())
+
+		if err != nil {
+			return failed(nil, localise(msgFailCompare, expectation.extension, err.Error()))
+		}
+
+		if !success {
+			return failed(diff, localise(msgFailDiff, expectation.extension))
+		}
+	}
+
+	if expectations == 0 {
+		return testResult{status: testSkipped, message: localise(msgSkipNoExpectation), duration: time.Since(now), coverage: coverage}
+	}
+
+	return testResult{status: testPassed, duration: time.Since(now), coverage: coverage}
+}
+
+// runWithRetries runs a test case, running it again up to the given number of
+// times if it fails. The result is that of the last attempt.
+func (r testRunner) runWithRetries(tc testCase, retries int) (result testResult) {
+
+	attempts := 0
+
+	retryPolicy{retries: retries}.do(func(context.Context) error {
+
+		attempts++
+		result = r.run(tc)
+
+		if result.status == testFailed {
+			return fmt.Errorf("%s", result.message)
+		}
+
+		return nil
+	}, nil)
+
+	result.attempts = attempts
+
+	return
+}
+
+func (r testRunner) parser() (scl.Parser, error) {
+
+	parser, err := configuredParser(r.params, r.environment, r.includePaths, r.workspace, r.ignoreCase)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parser.SetOutputFormat(r.format)
+
+	return parser, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var testTagsMatcher = regexp.MustCompile(`^\s*//\s*scl:tags\s+(.+)$`)
+var testMutateMatcher = regexp.MustCompile(`^\s*//\s*scl:mutate\s+(.+)$`)
+
+// A testCase is a single .scl file run by scl test, along with any metadata
+// declared in its comments.
+type testCase struct {
+	fileName  string
+	tags      []string
+	mutations []string
+}
+
+/*
+loadTestCase reads the metadata for a test case. Tags are declared with a line
+comment anywhere in the file, separated by spaces or commas:
+
+	// scl:tags slow, network
+
+The parameters that the output should depend on, which are perturbed by
+scl test --mutate, are declared in the same way:
+
+	// scl:mutate region, environment
+*/
+func loadTestCase(fileName string) (testCase, error) {
+
+	tc := testCase{fileName: fileName}
+
+	f, err := os.Open(fileName)
+
+	if err != nil {
+		return tc, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		if matches := testTagsMatcher.FindStringSubmatch(scanner.Text()); matches != nil {
+			tc.tags = append(tc.tags, splitTags(matches[1])...)
+		}
+
+		if matches := testMutateMatcher.FindStringSubmatch(scanner.Text()); matches != nil {
+			tc.mutations = append(tc.mutations, splitTags(matches[1])...)
+		}
+	}
+
+	return tc, scanner.Err()
+}
+
+func splitTags(s string) []string {
+	return strings.FieldsFunc(s, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t'
+	})
+}
+
+func (tc testCase) hasTag(tag string) bool {
+
+	for _, t := range tc.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// A testFilter selects which test cases to run, by name and by tag.
+type testFilter struct {
+	run      *regexp.Regexp
+	included []string
+	excluded []string
+}
+
+/*
+newTestFilter creates a filter from a --run regular expression and a --tags
+list. Tags prefixed with ! exclude cases with that tag; if there are any other
+tags, only cases with at least one of them are run.
+*/
+func newTestFilter(run, tags string) (f testFilter, err error) {
+
+	if run != "" {
+		if f.run, err = regexp.Compile(run); err != nil {
+			return f, err
+		}
+	}
+
+	for _, tag := range splitTags(tags) {
+		if strings.HasPrefix(tag, "!") {
+			f.excluded = append(f.excluded, strings.TrimPrefix(tag, "!"))
+		} else {
+			f.included = append(f.included, tag)
+		}
+	}
+
+	return f, nil
+}
+
+func (f testFilter) matches(tc testCase) bool {
+
+	if f.run != nil && !f.run.MatchString(tc.fileName) {
+		return false
+	}
+
+	for _, tag := range f.excluded {
+		if tc.hasTag(tag) {
+			return false
+		}
+	}
+
+	if len(f.included) == 0 {
+		return true
+	}
+
+	for _, tag := range f.included {
+		if tc.hasTag(tag) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultSlowestTests = 10
+
+// A timingReport records how long each test case took to run.
+type timingReport struct {
+	mutex     sync.Mutex
+	durations map[string]time.Duration
+}
+
+func newTimingReport() *timingReport {
+	return &timingReport{durations: make(map[string]time.Duration)}
+}
+
+func (t *timingReport) add(fileName string, duration time.Duration) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.durations[fileName] += duration
+}
+
+// write prints the slowest test cases, followed by the total time spent on
+// the tests in each directory, slowest first.
+func (t *timingReport) write(w io.Writer, slowest int) {
+
+	if len(t.durations) == 0 {
+		return
+	}
+
+	type timing struct {
+		name     string
+		duration time.Duration
+	}
+
+	sorted := func(durations map[string]time.Duration) []timing {
+
+		timings := make([]timing, 0, len(durations))
+
+		for name, duration := range durations {
+			timings = append(timings, timing{name, duration})
+		}
+
+		sort.Slice(timings, func(i, j int) bool {
+
+			if timings[i].duration != timings[j].duration {
+				return timings[i].duration > timings[j].duration
+			}
+
+			return timings[i].name < timings[j].name
+		})
+
+		return timings
+	}
+
+	files := sorted(t.durations)
+	dirs := make(map[string]time.Duration)
+
+	for _, f := range files {
+		dirs[filepath.Dir(f.name)] += f.duration
+	}
+
+	if slowest > len(files) {
+		slowest = len(files)
+	}
+
+	fmt.Fprint(w, localise(msgSlowestTests, slowest))
+
+	for _, f := range files[:slowest] {
+		fmt.Fprintf(w, "\t%.3fs\t%s\n", f.duration.Seconds(), f.name)
+	}
+
+	fmt.Fprint(w, localise(msgTimePerDirectory))
+
+	for _, d := range sorted(dirs) {
+		fmt.Fprintf(w, "\t%.3fs\t%s\n", d.duration.Seconds(), d.name)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/vcs"
+)
+
+// Hosts whose URLs are known to be repositories, so there's no need to look
+// for a vanity import tag
+var knownHosts = []string{
+	"github.com/",
+	"bitbucket.org/",
+	"gitlab.com/",
+	"launchpad.net/",
+}
+
+var metaTagMatcher = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+var metaAttributeMatcher = regexp.MustCompile(`(?is)(name|content)\s*=\s*["']([^"']*)["']`)
+
+var vanityClient = &http.Client{Timeout: 30 * time.Second}
+
+// vanityImport is the content of a go-import meta tag: the import prefix, the
+// type of VCS and the real location of the repository.
+type vanityImport struct {
+	prefix  string
+	vcs     string
+	repoURL string
+}
+
+func (v vanityImport) repo(local string) (vcs.Repo, error) {
+
+	switch vcs.Type(v.vcs) {
+	case vcs.Git:
+		return vcs.NewGitRepo(v.repoURL, local)
+	case vcs.Hg:
+		return vcs.NewHgRepo(v.repoURL, local)
+	case vcs.Svn:
+		return vcs.NewSvnRepo(v.repoURL, local)
+	case vcs.Bzr:
+		return vcs.NewBzrRepo(v.repoURL, local)
+	}
+
+	return nil, fmt.Errorf("Unsupported VCS %s for %s", v.vcs, v.repoURL)
+}
+
+func isKnownHost(dep string) bool {
+
+	for _, host := range knownHosts {
+		if strings.HasPrefix(dep, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+resolveVanity looks up a go-get style vanity URL by requesting it with the
+?go-get=1 query parameter and reading the go-import meta tag in the response,
+for example:
+
+	<meta name="go-import" content="example.com/scl/lib git https://github.com/example/lib">
+
+It returns nil if the page doesn't have a matching tag.
+*/
+func resolveVanity(dep string) (*vanityImport, error) {
+
+	response, err := vanityClient.Get("https://" + dep + "?go-get=1")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	// Meta tags are in the head, so there's no need to read a whole page
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, 1<<20))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range metaTagMatcher.FindAllString(string(body), -1) {
+
+		attributes := map[string]string{}
+
+		for _, attribute := range metaAttributeMatcher.FindAllStringSubmatch(tag, -1) {
+			attributes[strings.ToLower(attribute[1])] = attribute[2]
+		}
+
+		if attributes["name"] != "go-import" {
+			continue
+		}
+
+		fields := strings.Fields(attributes["content"])
+
+		if len(fields) != 3 {
+			continue
+		}
+
+		if dep == fields[0] || strings.HasPrefix(dep, fields[0]+"/") {
+			return &vanityImport{prefix: fields[0], vcs: fields[1], repoURL: fields[2]}, nil
+		}
+	}
+
+	return nil, nil
+}
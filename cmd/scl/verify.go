@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/homemade/scl"
+)
+
+/*
+verifyDependency parses every .scl file under the root of a dependency, and
+returns an error for each one that fails. The vendor directory is used as an
+include path, so that libraries can include their own dependencies.
+*/
+func verifyDependency(root, vendorDir string) (failures []error, err error) {
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".scl" {
+			return nil
+		}
+
+		parser, err := scl.NewParser(scl.NewDiskSystem())
+
+		if err != nil {
+			return err
+		}
+
+		parser.AddIncludePath(vendorDir)
+
+		if err := parser.Parse(path); err != nil {
+			rel, _ := filepath.Rel(root, path)
+			failures = append(failures, fmt.Errorf("%s: %s", rel, err))
+		}
+
+		return nil
+	})
+
+	return
+}
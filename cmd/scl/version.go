@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/tucnak/climax"
+)
+
+/*
+The build information is set by the linker, so that a binary can be traced
+back to the exact source it was built from:
+
+	go build -ldflags "-X main.commit=$(git rev-parse HEAD) \
+	  -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ) \
+	  -X main.dependencies=github.com/hashicorp/hcl@7fa7fff,..."
+
+dependencies is a comma-separated list of package@version pairs, usually taken
+from glide.lock. Anything not set by the linker is taken from the build
+information the go tool embeds in module builds, such as those made by go
+install, and anything not found there either is reported as unknown.
+*/
+var (
+	version      = "1.3.1"
+	commit       = ""
+	buildDate    = ""
+	dependencies = ""
+)
+
+type buildInfo struct {
+	Version      string            `json:"version"`
+	Commit       string            `json:"commit"`
+	BuildDate    string            `json:"build_date"`
+	GoVersion    string            `json:"go_version"`
+	Platform     string            `json:"platform"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+func currentBuildInfo() buildInfo {
+
+	unknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+
+	info := buildInfo{
+		Version:      version,
+		Commit:       unknown(commit),
+		BuildDate:    unknown(buildDate),
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		Dependencies: map[string]string{},
+	}
+
+	for _, dep := range strings.Split(dependencies, ",") {
+		if parts := strings.SplitN(strings.TrimSpace(dep), "@", 2); len(parts) == 2 {
+			info.Dependencies[parts[0]] = parts[1]
+		}
+	}
+
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		info.addEmbedded(embedded)
+	}
+
+	return info
+}
+
+// addEmbedded fills in anything the linker didn't set from the build
+// information embedded by the go tool.
+func (b *buildInfo) addEmbedded(embedded *debug.BuildInfo) {
+
+	if v := embedded.Main.Version; v != "" && v != "(devel)" {
+		b.Version = strings.TrimPrefix(v, "v")
+	}
+
+	settings := make(map[string]string)
+
+	for _, s := range embedded.Settings {
+		settings[s.Key] = s.Value
+	}
+
+	if b.Commit == "unknown" && settings["vcs.revision"] != "" {
+
+		b.Commit = settings["vcs.revision"]
+
+		if settings["vcs.modified"] == "true" {
+			b.Commit += "-dirty"
+		}
+	}
+
+	// The time of the commit is the closest the go tool records to a build date
+	if b.BuildDate == "unknown" && settings["vcs.time"] != "" {
+		b.BuildDate = settings["vcs.time"]
+	}
+
+	if len(b.Dependencies) == 0 {
+		for _, dep := range embedded.Deps {
+			b.Dependencies[dep.Path] = dep.Version
+		}
+	}
+}
+
+func (b buildInfo) String() string {
+	return fmt.Sprintf("scl %s (commit %s, built %s)", b.Version, b.Commit, b.BuildDate)
+}
+
+func versionCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "version",
+		Brief: "Print the version and build information",
+		Usage: `[options]`,
+		Help:  `Print the version of scl, the commit and date it was built from, and the versions of the packages it was built with.`,
+
+		Flags: []climax.Flag{
+			{
+				Name:  "json",
+				Short: "j",
+				Usage: `--json`,
+				Help:  `Print the build information as JSON`,
+			},
+		},
+
+		Handle: func(ctx climax.Context) int {
+
+			info := currentBuildInfo()
+
+			if ctx.Is("json") {
+
+				output, err := json.MarshalIndent(info, "", "  ")
+
+				if err != nil {
+					fmt.Fprint(stderr, localise(msgRenderJSON, err.Error()))
+					return 1
+				}
+
+				fmt.Fprintf(stdout, "%s\n", output)
+				return 0
+			}
+
+			fmt.Fprintln(stdout, info)
+			fmt.Fprintf(stdout, "%s %s\n", info.GoVersion, info.Platform)
+
+			names := make([]string, 0, len(info.Dependencies))
+
+			for name := range info.Dependencies {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+
+			for _, name := range names {
+				fmt.Fprintf(stdout, "\t%s %s\n", name, info.Dependencies[name])
+			}
+
+			return 0
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/tucnak/climax"
+)
+
+func vetCommand(stdout io.Writer, stderr io.Writer) climax.Command {
+
+	return climax.Command{
+		Name:  "vet",
+		Brief: "Check the output of .scl files against policies",
+		Usage: `[options] --policy <policy.rego> <filename.scl...>`,
+		Help:  "Render each .scl file and evaluate the output against one or more OPA/Rego policies, failing if any policy denies it. The opa binary must be installed.",
+
+		Flags: append(standardParserParams(),
+			climax.Flag{
+				Name:     "policy",
+				Usage:    `--policy policy.rego,/path/to/policies`,
+				Help:     `Comma-separated list of Rego files or directories of policies`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "query",
+				Usage:    `--query data.scl.deny`,
+				Help:     `The query that produces denial messages. Default is data.scl.deny`,
+				Variable: true,
+			},
+			climax.Flag{
+				Name:     "opa",
+				Usage:    `--opa /path/to/opa`,
+				Help:     `The opa binary to run. Default is opa on the PATH`,
+				Variable: true,
+			},
+		),
+
+		Handle: func(ctx climax.Context) int {
+
+			if len(ctx.Args) == 0 {
+				fmt.Fprint(stderr, localise(msgFilenameRequired, "vet"))
+				return 1
+			}
+
+			policies, set := ctx.Get("policy")
+
+			if !set || policies == "" {
+				fmt.Fprint(stderr, localise(msgPolicyRequired))
+				return 1
+			}
+
+			evaluator := policyEvaluator{
+				binary:   "opa",
+				policies: strings.Split(policies, ","),
+				query:    defaultPolicyQuery,
+			}
+
+			if query, set := ctx.Get("query"); set {
+				evaluator.query = query
+			}
+
+			if binary, set := ctx.Get("opa"); set {
+				evaluator.binary = binary
+			}
+
+			if _, err := exec.LookPath(evaluator.binary); err != nil {
+				fmt.Fprint(stderr, localise(msgFindOPA, err.Error()))
+				return 1
+			}
+
+			params, environment, includePaths, err := parserParams(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadParams, err.Error()))
+				return 1
+			}
+
+			stderr := params.redactor().writer(stderr)
+
+			workspace, err := parserWorkspace(ctx)
+
+			if err != nil {
+				fmt.Fprint(stderr, localise(msgLoadWorkspace, err.Error()))
+				return 1
+			}
+
+			caseInsensitive, err := includesIgnoreCase(ctx)
+
+			if err != nil {
+				fmt.Fprintf(stderr, "%s\n", err.Error())
+				return 1
+			}
+
+			errors := 0
+			output := newSyncOutput(stdout, stderr)
+
+			vetFile := func(fileName string, out *fileOutput) {
+
+				parser, err := configuredParser(params, environment, includePaths, workspace, caseInsensitive)
+
+				if err != nil {
+					out.fail(fileName, "%s", localise(msgFailCreateParser, err.Error()))
+					return
+				}
+
+				if err := parser.Parse(fileName); err != nil {
+					out.fail(fileName, "%s", localise(msgFailParseFile, err.Error()))
+					return
+				}
+
+				input, err := renderJSON(parser.String())
+
+				if err != nil {
+					out.fail(fileName, "%s", localise(msgFailRenderJSON, err.Error()))
+					return
+				}
+
+				denials, err := evaluator.evaluate(input)
+
+				if err != nil {
+					out.fail(fileName, "%s", localise(msgFailEvaluatePolicies, err.Error()))
+					return
+				}
+
+				if len(denials) == 0 {
+					fmt.Fprintf(&out.stdout, "%-7s %s\n", "ok", fileName)
+					return
+				}
+
+				out.fail(fileName, "%s", localise(msgFailDenied))
+
+				for _, d := range denials {
+					fmt.Fprintf(&out.stderr, "\t%s\n", d)
+				}
+			}
+
+			for _, fileName := range ctx.Args {
+
+				out := &fileOutput{}
+				vetFile(fileName, out)
+				output.flush(out)
+
+				errors += out.failures
+			}
+
+			if errors > 0 {
+				fmt.Fprint(stderr, localise(msgFailedErrors, errors))
+				return 1
+			}
+
+			return 0
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/homemade/scl"
+)
+
+// watchDebounce is how long to wait for further changes after the first one,
+// so that saving several files at once only causes a single run.
+const watchDebounce = 200 * time.Millisecond
+
+// A fileWatch merges the change events for a growing set of files, until it's
+// stopped.
+type fileWatch struct {
+	watcher scl.Watcher
+	watched map[string]bool
+	events  chan scl.Event
+	ctx     context.Context
+	stop    context.CancelFunc
+}
+
+func newFileWatch() (*fileWatch, error) {
+
+	watcher, ok := scl.NewDiskSystem().(scl.Watcher)
+
+	if !ok {
+		return nil, fmt.Errorf("The filesystem can't be watched")
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &fileWatch{
+		watcher: watcher,
+		watched: make(map[string]bool),
+		events:  make(chan scl.Event),
+		ctx:     ctx,
+		stop:    stop,
+	}, nil
+}
+
+// add starts watching any of the given files that aren't already watched.
+func (w *fileWatch) add(paths ...string) error {
+
+	for _, path := range paths {
+
+		if w.watched[path] {
+			continue
+		}
+
+		events, err := w.watcher.Watch(w.ctx, path)
+
+		if err != nil {
+			return err
+		}
+
+		w.watched[path] = true
+
+		go func() {
+			for e := range events {
+				select {
+				case w.events <- e:
+				case <-w.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// wait blocks until a watched file changes, then returns the first change
+// once the changes have settled down. It returns false if the watch is
+// stopped first.
+func (w *fileWatch) wait() (scl.Event, bool) {
+
+	var first scl.Event
+
+	select {
+	case first = <-w.events:
+	case <-w.ctx.Done():
+		return first, false
+	}
+
+	for {
+		select {
+		case <-w.events:
+		case <-time.After(watchDebounce):
+			return first, true
+		case <-w.ctx.Done():
+			return first, false
+		}
+	}
+}
+
+// watchInputs returns a file and everything it includes. If the includes
+// can't be worked out, only the file itself is returned.
+func watchInputs(parser scl.Parser, fileName string) []string {
+
+	includes, err := parser.Includes(fileName)
+
+	if err != nil {
+		return []string{fileName}
+	}
+
+	return append([]string{fileName}, includes...)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/tucnak/climax"
+
+	"github.com/homemade/scl"
+)
+
+// findWorkspace looks for a workspace file in the given directory and each of
+// its parents, returning an empty string if there isn't one.
+func findWorkspace(dir string) string {
+	return findUpwards(dir, scl.WorkspaceFileName)
+}
+
+// findUpwards looks for a file with the given name in a directory and each of
+// its parents, returning an empty string if there isn't one.
+func findUpwards(dir, name string) string {
+
+	for {
+		path := filepath.Join(dir, name)
+
+		if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// parserWorkspace loads the workspace given on the command line or, failing
+// that, the nearest one to the current directory.
+func parserWorkspace(ctx climax.Context) (scl.Workspace, error) {
+
+	if ctx.Is("no-workspace") {
+		return nil, nil
+	}
+
+	path, set := ctx.Get("workspace")
+
+	if !set {
+
+		cwd, err := os.Getwd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if path = findWorkspace(cwd); path == "" {
+			return nil, nil
+		}
+	}
+
+	return scl.LoadWorkspace(scl.NewDiskSystem(), path)
+}
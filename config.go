@@ -0,0 +1,87 @@
+package scl
+
+import "context"
+
+/*
+A Config holds everything needed to create a Parser in one place, as an
+alternative to calling the Parser's setters one at a time. If the FileSystem
+is nil, the local disk is used. It only needs to be a Reader, though without
+a Glober includes can't use wildcards.
+
+The variables in the Environment are imported as string params, in order of
+name, before the Params, so a param replaces a variable with the same name. The
+Environment is empty by default: use SnapshotEnvironment() to import the
+process environment.
+
+If CaseInsensitive is set, includes match files whatever the case of their
+names, as they do on the default filesystems of macOS and Windows. A name that
+matches more than one file in different cases resolves to the file in the same
+case, or else the first, and the Parser's Warnings() lists the ambiguity.
+
+VirtualFiles are added to the Parser as if by AddVirtualFile, and the
+Preprocessor, Postprocessor and OutputFormat are set as if by SetPreprocessor,
+SetPostprocessor and SetOutputFormat.
+*/
+type Config struct {
+	FileSystem      Reader
+	Params          map[string]string
+	Environment     Environment
+	IncludePaths    []string
+	Workspace       Workspace
+	VirtualFiles    map[string][]byte
+	Preprocessor    Preprocessor
+	Postprocessor   Postprocessor
+	OutputFormat    OutputFormat
+	CaseInsensitive bool
+}
+
+/*
+NewParserFromConfig creates a new, standard Parser from a Config.
+*/
+func NewParserFromConfig(config Config) (Parser, error) {
+
+	fs := config.FileSystem
+
+	if fs == nil {
+		fs = NewDiskSystem()
+	}
+
+	virtual := newVirtualFileSystem(fs)
+
+	p := &parser{
+		fs:              virtual,
+		virtual:         virtual,
+		rootScope:       newScope(),
+		workspace:       Workspace{},
+		coverage:        coverage{},
+		inputs:          newInputs(),
+		ctx:             context.Background(),
+		preprocessor:    config.Preprocessor,
+		postprocessor:   config.Postprocessor,
+		format:          config.OutputFormat,
+		environment:     config.Environment,
+		caseInsensitive: config.CaseInsensitive,
+	}
+
+	for _, name := range config.Environment.names {
+		p.SetParam(name, config.Environment.param(name))
+	}
+
+	for name, value := range config.Params {
+		p.SetParam(name, value)
+	}
+
+	for _, path := range config.IncludePaths {
+		p.AddIncludePath(path)
+	}
+
+	for name, path := range config.Workspace {
+		p.AddWorkspaceLibrary(name, path)
+	}
+
+	for name, content := range config.VirtualFiles {
+		p.AddVirtualFile(name, content)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,25 @@
+package scl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AParserCanBeCreatedFromAConfig(t *testing.T) {
+
+	p0, err := NewParserFromConfig(Config{
+		Params:       map[string]string{"myVar": "1"},
+		IncludePaths: []string{"fixtures/valid"},
+		Workspace:    Workspace{"github.com/myorg/lib": "fixtures/workspace/shared"},
+	})
+
+	require.Nil(t, err)
+
+	p := p0.(*parser)
+
+	require.NotNil(t, p.fs)
+	require.Equal(t, "1", p.rootScope.variable("myVar"))
+	require.Equal(t, []string{"fixtures/valid"}, p.includePaths)
+	require.Equal(t, Workspace{"github.com/myorg/lib": "fixtures/workspace/shared"}, p.workspace)
+}
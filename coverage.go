@@ -0,0 +1,166 @@
+package scl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+A CoverageKind is the kind of statement recorded in a CoverageBlock.
+*/
+type CoverageKind string
+
+const (
+	// CoverageMixin is a mixin declaration, covered when the mixin is called.
+	CoverageMixin CoverageKind = "mixin"
+
+	// CoverageInclude is an include statement, covered when it's executed.
+	CoverageInclude CoverageKind = "include"
+)
+
+/*
+CoverageBlock records how many times a statement was used while parsing. Only
+statements the parser reached are recorded: a mixin declared inside another
+mixin is only recorded if the outer mixin was called, for example.
+*/
+type CoverageBlock struct {
+	Kind  CoverageKind
+	Name  string
+	File  string
+	Line  int
+	Count int
+}
+
+/*
+CoverageBlocks is a slice of CoverageBlocks, for convenience. A Parser returns
+its coverage sorted by file and line.
+*/
+type CoverageBlocks []CoverageBlock
+
+type coverage map[string]*CoverageBlock
+
+func (c coverage) declare(kind CoverageKind, name string, line *scannerLine) *CoverageBlock {
+
+	key := fmt.Sprintf("%s:%s", kind, line.String())
+
+	if b, ok := c[key]; ok {
+		return b
+	}
+
+	b := &CoverageBlock{
+		Kind: kind,
+		Name: name,
+		File: line.file,
+		Line: line.line,
+	}
+
+	c[key] = b
+
+	return b
+}
+
+func (c coverage) use(kind CoverageKind, name string, line *scannerLine) {
+	c.declare(kind, name, line).Count++
+}
+
+func (c coverage) blocks() CoverageBlocks {
+
+	blocks := make(CoverageBlocks, 0, len(c))
+
+	for _, b := range c {
+		blocks = append(blocks, *b)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool {
+
+		if blocks[i].File != blocks[j].File {
+			return blocks[i].File < blocks[j].File
+		}
+
+		if blocks[i].Line != blocks[j].Line {
+			return blocks[i].Line < blocks[j].Line
+		}
+
+		return blocks[i].Kind < blocks[j].Kind
+	})
+
+	return blocks
+}
+
+/*
+Statements lists the mixin declarations and include statements in a file, at
+any depth, as CoverageBlocks with a count of zero. Unlike Coverage(), the file
+isn't parsed, so statements are listed even if parsing would never reach them.
+*/
+func (p *parser) Statements(fileName string) (CoverageBlocks, error) {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tkn := newTokeniser()
+	statements := make(coverage)
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return p.err(branch, err.Error())
+			}
+
+			if len(tokens) == 0 || tokens[0].kind == tokenCommentStart {
+				continue
+			}
+
+			switch {
+			case tokens[0].kind == tokenMixinDeclaration:
+				statements.declare(CoverageMixin, tokens[0].content, branch)
+
+			case tokens[0].kind == tokenFunctionCall && (tokens[0].content == builtinMixinInclude || tokens[0].content == builtinMixinPrefixed):
+
+				fileTokens, _ := splitIncludeArguments(tokens[1:])
+
+				// The first argument to include_prefixed() is the prefix
+				if tokens[0].content == builtinMixinPrefixed && len(fileTokens) > 0 {
+					fileTokens = fileTokens[1:]
+				}
+
+				names := make([]string, len(fileTokens))
+
+				for i, t := range fileTokens {
+					names[i] = strings.Trim(t.content, `"'`)
+				}
+
+				statements.declare(CoverageInclude, strings.Join(names, ", "), branch)
+
+			case tokens[0].kind == tokenFunctionCall && tokens[0].content == builtinMixinInstance:
+
+				// Only the first argument to instance() is a file; the second
+				// is the prefix
+				if fileTokens, _ := splitIncludeArguments(tokens[1:]); len(fileTokens) > 0 {
+					statements.declare(CoverageInclude, strings.Trim(fileTokens[0].content, `"'`), branch)
+				}
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(lines); err != nil {
+		return nil, err
+	}
+
+	return statements.blocks(), nil
+}
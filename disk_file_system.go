@@ -2,6 +2,7 @@ package scl
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,6 +16,11 @@ type diskFileSystem struct {
 /*
 NewDiskSystem creates a filesystem that uses the local disk, at an optional
 base path. The default base path is the current working directory.
+
+Globs on a disk filesystem treat ** as any number of directories, and skip the
+paths listed in a .sclignore file in the base path, which uses the same syntax
+as a .gitignore file. Vendor and node_modules directories are only searched by
+** if the pattern names them. A path without wildcards is never ignored.
 */
 func NewDiskSystem(basePath ...string) FileSystem {
 
@@ -32,7 +38,135 @@ func (d *diskFileSystem) path(path string) string {
 }
 
 func (d *diskFileSystem) Glob(pattern string) (out []string, err error) {
-	return filepath.Glob(d.path(pattern))
+
+	full := d.path(pattern)
+
+	if !hasWildcards(full) {
+		return filepath.Glob(full)
+	}
+
+	rules, err := d.ignoreRules()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(pattern, "**") {
+		return d.walkGlob(full, rules)
+	}
+
+	matches, err := filepath.Glob(full)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+
+		info, err := os.Stat(match)
+
+		if err == nil && rules.ignored(d.ignorePath(match), info.IsDir()) {
+			continue
+		}
+
+		out = append(out, match)
+	}
+
+	return out, nil
+}
+
+// walkGlob lists the matches for a pattern containing **, which matches any
+// number of directories, by walking the directories below the part of the
+// pattern without wildcards. Ignored directories aren't walked, and nor are
+// vendor and node_modules unless the pattern names them.
+func (d *diskFileSystem) walkGlob(pattern string, rules ignoreRules) (out []string, err error) {
+
+	slashed := filepath.ToSlash(pattern)
+	parts := strings.Split(slashed, "/")
+	wildcard := 0
+
+	for wildcard < len(parts) && !hasWildcards(parts[wildcard]) {
+		wildcard++
+	}
+
+	root := strings.Join(parts[:wildcard], "/")
+
+	if root == "" && strings.HasPrefix(slashed, "/") {
+		root = "/"
+	} else if root == "" {
+		root = "."
+	}
+
+	named := make(map[string]bool)
+
+	for _, part := range parts[wildcard:] {
+		named[part] = true
+	}
+
+	err = filepath.Walk(filepath.FromSlash(root), func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+
+			if os.IsNotExist(err) && path == filepath.FromSlash(root) {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if path == filepath.FromSlash(root) {
+			return nil
+		}
+
+		if info.IsDir() && unwalkedDirectories[info.Name()] && !named[info.Name()] {
+			return filepath.SkipDir
+		}
+
+		if rules.ignored(d.ignorePath(path), info.IsDir()) {
+
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if ok, err := matchPath(slashed, filepath.ToSlash(filepath.Clean(path))); err != nil {
+			return err
+		} else if ok {
+			out = append(out, path)
+		}
+
+		return nil
+	})
+
+	return out, err
+}
+
+// ignoreRules reads the ignore file in the base path, if there is one. It's
+// read for every glob so that changes to it take effect immediately.
+func (d *diskFileSystem) ignoreRules() (ignoreRules, error) {
+
+	content, err := ioutil.ReadFile(filepath.Join(d.basePath, ignoreFileName))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return parseIgnoreRules(string(content)), nil
+}
+
+// ignorePath is the slash-separated path of a file relative to the ignore
+// file.
+func (d *diskFileSystem) ignorePath(path string) string {
+
+	if relative, err := filepath.Rel(filepath.Clean(d.basePath+"/."), path); err == nil && !strings.HasPrefix(relative, "..") {
+		return filepath.ToSlash(relative)
+	}
+
+	return filepath.ToSlash(path)
 }
 
 func (d *diskFileSystem) ReadCloser(path string) (data io.ReadCloser, lastModified time.Time, err error) {
@@ -51,3 +185,18 @@ func (d *diskFileSystem) ReadCloser(path string) (data io.ReadCloser, lastModifi
 
 	return reader, stat.ModTime(), nil
 }
+
+func (d *diskFileSystem) RealPath(path string) (string, error) {
+
+	real, err := filepath.EvalSymlinks(d.path(path))
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(real)
+}
+
+func (d *diskFileSystem) WriteFile(path string, content []byte) error {
+	return ioutil.WriteFile(d.path(path), content, 0644)
+}
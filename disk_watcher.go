@@ -0,0 +1,126 @@
+package scl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// diskWatchInterval is how often a watched path is checked for changes.
+var diskWatchInterval = 500 * time.Millisecond
+
+type diskFileState struct {
+	modified time.Time
+	size     int64
+}
+
+/*
+Watch reports changes to a file or, for a directory, to any file beneath it.
+The disk is polled rather than using OS notifications, so that the package
+doesn't need any platform-specific dependencies; changes are reported within
+about half a second. The watch ends, and the channel is closed, when the
+context is done.
+*/
+func (d *diskFileSystem) Watch(ctx context.Context, path string) (<-chan Event, error) {
+
+	root := d.path(path)
+
+	state, err := diskSnapshot(root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	ticker := time.NewTicker(diskWatchInterval)
+
+	go func() {
+
+		defer close(events)
+		defer ticker.Stop()
+
+		// send gives up if the watch ends while the receiver isn't reading
+		send := func(e Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// A watched path that's been removed has no files in it
+			next, err := diskSnapshot(root)
+
+			if err != nil {
+				next = map[string]diskFileState{}
+			}
+
+			for p, s := range next {
+
+				e := Event{Path: d.relative(p), Op: EventWrite}
+
+				if old, ok := state[p]; !ok {
+					e.Op = EventCreate
+				} else if old.modified.Equal(s.modified) && old.size == s.size {
+					continue
+				}
+
+				if !send(e) {
+					return
+				}
+			}
+
+			for p := range state {
+				if _, ok := next[p]; !ok && !send(Event{Path: d.relative(p), Op: EventRemove}) {
+					return
+				}
+			}
+
+			state = next
+		}
+	}()
+
+	return events, nil
+}
+
+// relative is the inverse of path, so that events use the same names as the
+// caller.
+func (d *diskFileSystem) relative(path string) string {
+
+	if d.basePath == "" {
+		return path
+	}
+
+	return strings.TrimPrefix(strings.TrimPrefix(path, filepath.Clean(d.basePath)), string(filepath.Separator))
+}
+
+func diskSnapshot(root string) (map[string]diskFileState, error) {
+
+	state := make(map[string]diskFileState)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			state[path] = diskFileState{info.ModTime(), info.Size()}
+		}
+
+		return nil
+	})
+
+	return state, err
+}
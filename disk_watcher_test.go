@@ -0,0 +1,71 @@
+package scl
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ADiskFileSystemCanWatchForChanges(t *testing.T) {
+
+	interval := diskWatchInterval
+	diskWatchInterval = 10 * time.Millisecond
+	defer func() { diskWatchInterval = interval }()
+
+	dir, err := ioutil.TempDir("", "scl-watch")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fs := NewDiskSystem(dir)
+	w, ok := fs.(Watcher)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := w.Watch(ctx, "")
+	require.Nil(t, err)
+
+	next := func() Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("No event received")
+		}
+		return Event{}
+	}
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.scl"), []byte("a = 1"), 0644))
+	require.Equal(t, Event{Path: "a.scl", Op: EventCreate}, next())
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.scl"), []byte("a = 12"), 0644))
+	require.Equal(t, Event{Path: "a.scl", Op: EventWrite}, next())
+
+	require.Nil(t, os.Remove(filepath.Join(dir, "a.scl")))
+	require.Equal(t, Event{Path: "a.scl", Op: EventRemove}, next())
+
+	// Ending the watch closes the channel, even if an event is waiting to be
+	// received
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.scl"), []byte("b = 1"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	deadline := time.After(time.Second)
+
+	for {
+		select {
+		case _, open := <-events:
+			if !open {
+				return
+			}
+		case <-deadline:
+			t.Fatal("The watch didn't end")
+		}
+	}
+}
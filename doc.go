@@ -17,6 +17,11 @@ tutorials and examples, is available at https://github.com/homemade/scl/wiki.
 */
 package scl
 
+import (
+	"fmt"
+	"strings"
+)
+
 /*
 MixinDoc documents a mixin from a particular SCL file. Since mixins can be nested, it
 also includes a tree of all child mixins.
@@ -35,3 +40,73 @@ type MixinDoc struct {
 MixinDocs is a slice of MixinDocs, for convenience.
 */
 type MixinDocs []MixinDoc
+
+/*
+ExportDoc documents a name exported from a particular SCL file using the /export
+directive. Value holds the unevaluated value of the export, and is empty if the
+export refers to a mixin or variable declared elsewhere in the file.
+*/
+type ExportDoc struct {
+	Name      string
+	File      string
+	Line      int
+	Reference string
+	Value     string
+}
+
+/*
+ExportDocs is a slice of ExportDocs, for convenience.
+*/
+type ExportDocs []ExportDoc
+
+/*
+FileMetadata holds the metadata declared in the header of an SCL file, using the
+/author, /description, /version and /tags directives. Tags are given as a
+comma-separated list, and may be split across several /tags directives.
+Metadata has no effect on a file's output.
+*/
+type FileMetadata struct {
+	File        string
+	Author      string
+	Description string
+	Version     string
+	Tags        []string
+}
+
+func (m *FileMetadata) set(key, value string) error {
+
+	var field *string
+
+	switch key {
+	case "author":
+		field = &m.Author
+	case "description":
+		field = &m.Description
+	case "version":
+		field = &m.Version
+	case "tags":
+
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				m.Tags = append(m.Tags, tag)
+			}
+		}
+
+		return nil
+	}
+
+	if *field != "" {
+		return fmt.Errorf("Duplicate /%s directive", key)
+	}
+
+	*field = value
+
+	return nil
+}
+
+/*
+Empty reports whether a file declares no metadata.
+*/
+func (m FileMetadata) Empty() bool {
+	return m.Author == "" && m.Description == "" && m.Version == "" && len(m.Tags) == 0
+}
@@ -0,0 +1,90 @@
+package scl
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+/*
+An Environment is a snapshot of environment variables, to import into a Parser
+as params. It's taken once, so a long-lived Parser sees the same values however
+the process environment changes while it's parsing, and it can't be changed
+after it's been created. Variables are always listed in order of name, so that
+the order they're imported in never depends on the platform.
+*/
+type Environment struct {
+	names  []string
+	values map[string]string
+}
+
+/*
+SnapshotEnvironment takes a snapshot of the process environment.
+*/
+func SnapshotEnvironment() Environment {
+	return NewEnvironment(os.Environ())
+}
+
+/*
+NewEnvironment creates an Environment from name=value pairs, in the form
+returned by os.Environ(). A later pair replaces an earlier one with the same
+name, and anything without an equals sign or a name is ignored.
+*/
+func NewEnvironment(pairs []string) Environment {
+
+	e := Environment{values: make(map[string]string, len(pairs))}
+
+	for _, pair := range pairs {
+
+		parts := strings.SplitN(pair, "=", 2)
+
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+
+		if name == "" {
+			continue
+		}
+
+		if _, ok := e.values[name]; !ok {
+			e.names = append(e.names, name)
+		}
+
+		e.values[name] = parts[1]
+	}
+
+	sort.Strings(e.names)
+
+	return e
+}
+
+/*
+Names lists the names of the variables in the snapshot, in order.
+*/
+func (e Environment) Names() []string {
+	return append([]string{}, e.names...)
+}
+
+/*
+Lookup returns the value of a variable in the snapshot, and whether it's set.
+*/
+func (e Environment) Lookup(name string) (string, bool) {
+	value, ok := e.values[name]
+	return value, ok
+}
+
+/*
+Len returns the number of variables in the snapshot.
+*/
+func (e Environment) Len() int {
+	return len(e.names)
+}
+
+// param returns the value of a variable as a param: a string literal of the
+// value with surrounding whitespace removed, as the scl command has always
+// imported the environment.
+func (e Environment) param(name string) string {
+	return `"` + strings.TrimSpace(e.values[name]) + `"`
+}
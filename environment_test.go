@@ -0,0 +1,74 @@
+package scl
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AnEnvironmentIsASortedSnapshot(t *testing.T) {
+
+	for cycle, input := range []struct {
+		pairs  []string
+		names  []string
+		values map[string]string
+	}{
+		{
+			pairs:  nil,
+			names:  []string{},
+			values: map[string]string{},
+		},
+		{
+			pairs:  []string{"ZONE=b", "APP=web", "EMPTY="},
+			names:  []string{"APP", "EMPTY", "ZONE"},
+			values: map[string]string{"APP": "web", "EMPTY": "", "ZONE": "b"},
+		},
+		{
+			pairs:  []string{"A=1", "A=2", "B=x=y", "INVALID", "=nameless"},
+			names:  []string{"A", "B"},
+			values: map[string]string{"A": "2", "B": "x=y"},
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		e := NewEnvironment(input.pairs)
+		require.Equal(t, input.names, e.Names())
+		require.Equal(t, len(input.names), e.Len())
+
+		for name, value := range input.values {
+			v, ok := e.Lookup(name)
+			require.True(t, ok)
+			require.Equal(t, value, v)
+		}
+
+		// The names returned are a copy
+		if names := e.Names(); len(names) > 0 {
+			names[0] = "changed"
+			require.Equal(t, input.names, e.Names())
+		}
+	}
+}
+
+func Test_AParserImportsItsEnvironmentOnce(t *testing.T) {
+
+	require.Nil(t, os.Setenv("SCL_ENVIRONMENT_TEST", " before "))
+	defer os.Unsetenv("SCL_ENVIRONMENT_TEST")
+
+	p0, err := NewParserFromConfig(Config{
+		Environment: SnapshotEnvironment(),
+		Params:      map[string]string{"HOME": `"replaced"`},
+	})
+
+	require.Nil(t, err)
+	require.Nil(t, os.Setenv("SCL_ENVIRONMENT_TEST", "after"))
+
+	p := p0.(*parser)
+
+	require.Equal(t, `"before"`, p.rootScope.variable("SCL_ENVIRONMENT_TEST"))
+	require.Equal(t, `"replaced"`, p.rootScope.variable("HOME"))
+
+	value, ok := p.Environment().Lookup("SCL_ENVIRONMENT_TEST")
+	require.True(t, ok)
+	require.Equal(t, " before ", value)
+}
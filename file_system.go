@@ -1,7 +1,11 @@
 package scl
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,8 +16,121 @@ for this is a local disk filesystem, but it could be anything – records in a
 database, objects on AWS S3, the contents of a zip file, virtual files stored
 inside a binary, and so forth. A FileSystem is required to instantiate the
 standard Parser implementation.
+
+A FileSystem is made up of smaller capabilities, and only a Reader is strictly
+required: a filesystem that can't list its contents can still be parsed, as
+long as its includes don't use wildcards. Optional capabilities, such as
+writing and watching, are detected with a type assertion, and the features
+that need them are unavailable if they aren't implemented.
 */
 type FileSystem interface {
-	Glob(pattern string) ([]string, error)
+	Reader
+	Glober
+}
+
+/*
+A Reader can open a file by name.
+*/
+type Reader interface {
 	ReadCloser(path string) (content io.ReadCloser, lastModified time.Time, err error)
 }
+
+/*
+A Glober can list the files matching a glob pattern.
+*/
+type Glober interface {
+	Glob(pattern string) ([]string, error)
+}
+
+/*
+A Writer can replace the content of a file, creating it if necessary.
+*/
+type Writer interface {
+	WriteFile(path string, content []byte) error
+}
+
+/*
+A RealPather can resolve a path to the file it really refers to, following any
+symbolic links, so that a file reached by more than one path is recognised.
+*/
+type RealPather interface {
+	RealPath(path string) (string, error)
+}
+
+/*
+A Watcher can report changes to a file or a directory until the context is
+done. The channel is closed when the watch ends.
+*/
+type Watcher interface {
+	Watch(ctx context.Context, path string) (<-chan Event, error)
+}
+
+/*
+An Event describes a change to a watched file.
+*/
+type Event struct {
+	Path string
+	Op   EventOp
+}
+
+/*
+An EventOp is the kind of change described by an Event.
+*/
+type EventOp int
+
+const (
+	EventCreate EventOp = iota
+	EventWrite
+	EventRemove
+)
+
+func (o EventOp) String() string {
+
+	switch o {
+	case EventCreate:
+		return "create"
+	case EventWrite:
+		return "write"
+	case EventRemove:
+		return "remove"
+	}
+
+	return fmt.Sprintf("EventOp(%d)", int(o))
+}
+
+// realPath resolves a path using the filesystem's RealPather, if it has one.
+// Otherwise, or if the path can't be resolved, the path is only cleaned.
+func realPath(fs Reader, path string) string {
+
+	if r, ok := fs.(RealPather); ok {
+		if real, err := r.RealPath(path); err == nil {
+			return real
+		}
+	}
+
+	return filepath.Clean(path)
+}
+
+// glob lists the files matching a pattern using the filesystem's Glober, if
+// it has one. Otherwise, a pattern without any wildcards matches itself if
+// the file can be read, and a pattern with wildcards is an error.
+func glob(fs Reader, pattern string) ([]string, error) {
+
+	if g, ok := fs.(Glober); ok {
+		return g.Glob(pattern)
+	}
+
+	if strings.ContainsAny(pattern, `*?[\`) {
+		return nil, fmt.Errorf("Can't expand %s: the filesystem doesn't support wildcards", pattern)
+	}
+
+	f, _, err := fs.ReadCloser(pattern)
+
+	if err != nil {
+		return nil, nil
+	}
+
+	f.Close()
+
+	return []string{pattern}, nil
+}
@@ -0,0 +1,169 @@
+package scl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readerOnlyFileSystem implements only the Reader capability.
+type readerOnlyFileSystem map[string]string
+
+func (r readerOnlyFileSystem) ReadCloser(path string) (io.ReadCloser, time.Time, error) {
+
+	content, ok := r[path]
+
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("%s not found", path)
+	}
+
+	return ioutil.NopCloser(bytes.NewBufferString(content)), time.Time{}, nil
+}
+
+func Test_AParserCanReadFromAFileSystemThatCantGlob(t *testing.T) {
+
+	fs := readerOnlyFileSystem{
+		"main.scl":    "include(\"lib\")\nlibMixin()",
+		"lib.scl":     "@libMixin()\n  value = 1",
+		"glob.scl":    "include(\"lib*\")",
+		"missing.scl": "include(\"nothing\")",
+	}
+
+	for cycle, input := range []struct {
+		fileName string
+		output   string
+		err      error
+	}{
+		{
+			fileName: "main.scl",
+			output:   "value = 1",
+		},
+		{
+			fileName: "glob.scl",
+			err:      fmt.Errorf("[glob.scl:1] Can't expand vendor/lib*.scl: the filesystem doesn't support wildcards"),
+		},
+		{
+			fileName: "missing.scl",
+			err:      fmt.Errorf("[missing.scl:1] Can't read nothing.scl: no files found"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p, err := NewParserFromConfig(Config{FileSystem: fs})
+		require.Nil(t, err)
+
+		err = p.Parse(input.fileName)
+		require.Equal(t, input.err, err)
+
+		if err == nil {
+			require.Equal(t, input.output, p.String())
+		}
+	}
+}
+
+func Test_AFileIsOnlyIncludedOnceThroughSymbolicLinks(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "scl-symlink")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "lib", "real"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "lib", "real", "a.scl"), []byte("a = 1"), 0644))
+	require.Nil(t, os.Symlink(filepath.Join(dir, "lib", "real"), filepath.Join(dir, "lib", "link")))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.scl"), []byte(`include("lib/*/a", "lib/link/a")`), 0644))
+
+	p, err := NewParserFromConfig(Config{FileSystem: NewDiskSystem(dir)})
+	require.Nil(t, err)
+	require.Nil(t, p.Parse(filepath.Join(dir, "main.scl")))
+	require.Equal(t, "a = 1", p.String())
+}
+
+func Test_IncludesCanBeCaseInsensitive(t *testing.T) {
+
+	for cycle, input := range []struct {
+		caseInsensitive bool
+		include         string
+		output          string
+		err             error
+		warnings        []string
+	}{
+		{
+			include: "LIB/NETWORK",
+			err:     fmt.Errorf("[main.scl:1] Can't read LIB/NETWORK.scl: no files found"),
+		},
+		{
+			caseInsensitive: true,
+			include:         "LIB/NETWORK",
+			output:          "network = 1",
+			warnings:        []string{},
+		},
+		{
+			caseInsensitive: true,
+			include:         "lib/dns",
+			output:          "dns = 2",
+			warnings:        []string{"[main.scl:1] lib/dns.scl matches files that differ only by case: lib/DNS.scl, lib/dns.scl. Using lib/dns.scl"},
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p, err := NewParserFromConfig(Config{
+			FileSystem:      readerOnlyFileSystem{},
+			CaseInsensitive: input.caseInsensitive,
+			VirtualFiles: map[string][]byte{
+				"main.scl":        []byte(`include("` + input.include + `")`),
+				"lib/Network.scl": []byte("network = 1"),
+				"lib/DNS.scl":     []byte("dns = 1"),
+				"lib/dns.scl":     []byte("dns = 2"),
+			},
+		})
+		require.Nil(t, err)
+
+		err = p.Parse("main.scl")
+		require.Equal(t, input.err, err)
+
+		if err == nil {
+			require.Equal(t, input.output, p.String())
+			require.Equal(t, input.warnings, p.Warnings())
+		}
+	}
+}
+
+func Test_CaseInsensitiveIncludesOfIgnoredPathsAreRead(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "scl-ignore")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, ".sclignore"), []byte("lib/skip.scl\n"), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "lib", "skip.scl"), []byte("skip = 1"), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.scl"), []byte(`include("lib/skip.scl")`), 0644))
+
+	p, err := NewParserFromConfig(Config{FileSystem: NewDiskSystem(dir), CaseInsensitive: true})
+	require.Nil(t, err)
+	require.Nil(t, p.Parse(filepath.Join(dir, "main.scl")))
+	require.Equal(t, "skip = 1", p.String())
+}
+
+func Test_GlobPatternsCanBeMadeCaseInsensitive(t *testing.T) {
+
+	for cycle, input := range []struct {
+		pattern  string
+		expected string
+	}{
+		{"lib/a.scl", "[lL][iI][bB]/[aA].[sS][cC][lL]"},
+		{"v2/*_x", "[vV]2/*_[xX]"},
+		{"[ab]c", "[ab][cC]"},
+		{`\*d`, `\*[dD]`},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.expected, caseInsensitivePattern(input.pattern))
+	}
+}
@@ -0,0 +1,474 @@
+package scl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const builtinItemVariable = "item"
+
+var builtinCallMatcher = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)\s?\(`)
+var attributeValueMatcher = regexp.MustCompile(`^([^=]+=\s*)(.+)$`)
+
+/*
+A builtinFunction is a function that can be used as a value: on the right hand
+side of a variable assignment or an attribute, or as a mixin argument. Unlike
+mixins, builtin functions don't write any output; they return a new value.
+
+The arguments are passed through unevaluated, so that each function can decide
+how (and whether) to evaluate them in the given scope.
+*/
+type builtinFunction func(s *scope, args []string) (string, error)
+
+var builtinFunctions map[string]builtinFunction
+
+func init() {
+	builtinFunctions = map[string]builtinFunction{
+		"sort":      builtinSort,
+		"uniq":      builtinUniq,
+		"reverse":   builtinReverse,
+		"filter":    builtinFilter,
+		"map":       builtinMap,
+		"enumerate": builtinEnumerate,
+		"zip":       builtinZip,
+		"try":       builtinTry,
+		"can":       builtinCan,
+	}
+}
+
+// evaluateLiteral interpolates a literal. If the literal, or the value of the
+// attribute it declares, is a call to a builtin function, the call is
+// evaluated and its result is used instead.
+func (s *scope) evaluateLiteral(literal string) (string, error) {
+
+	if name, args, ok := splitFunctionCall(literal); ok {
+		if fn, exists := builtinFunctions[name]; exists {
+			return fn(s, args)
+		}
+	}
+
+	if parts := attributeValueMatcher.FindStringSubmatch(literal); parts != nil {
+
+		if name, args, ok := splitFunctionCall(parts[2]); ok {
+			if fn, exists := builtinFunctions[name]; exists {
+
+				key, err := s.interpolateLiteral(parts[1])
+
+				if err != nil {
+					return "", err
+				}
+
+				value, err := fn(s, args)
+
+				if err != nil {
+					return "", err
+				}
+
+				return key + value, nil
+			}
+		}
+	}
+
+	return s.interpolateLiteral(literal)
+}
+
+// evaluateArguments evaluates each of the arguments given to a builtin
+// function, checking that the expected number have been passed.
+func (s *scope) evaluateArguments(name string, args []string, expected int) ([]string, error) {
+
+	if len(args) != expected {
+		return nil, fmt.Errorf("Wrong number of arguments for %s (required %d, got %d)", name, expected, len(args))
+	}
+
+	values := make([]string, len(args))
+
+	for i, arg := range args {
+
+		value, err := s.evaluateLiteral(arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// evaluateList evaluates a single argument which must contain a list.
+func (s *scope) evaluateList(name string, args []string) ([]string, error) {
+
+	values, err := s.evaluateArguments(name, args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseList(values[0])
+}
+
+// splitFunctionCall splits a literal in the form name(arg0, arg1, ...) into
+// its name and its arguments. The literal must consist of nothing but the call.
+func splitFunctionCall(literal string) (name string, args []string, ok bool) {
+
+	literal = strings.TrimSpace(literal)
+
+	parts := builtinCallMatcher.FindStringSubmatch(literal)
+
+	if parts == nil || literal[len(literal)-1] != ')' {
+		return "", nil, false
+	}
+
+	inner := literal[len(parts[0]) : len(literal)-1]
+
+	args, err := splitValues(inner)
+
+	if err != nil {
+		return "", nil, false
+	}
+
+	return parts[1], args, true
+}
+
+// splitValues splits a comma-separated string into its top-level values,
+// respecting quotes, backtick literals and nested brackets or parentheses.
+func splitValues(input string) (values []string, err error) {
+
+	var (
+		depth     = 0
+		lastQuote = rune(0)
+		escaped   = false
+		current   = []rune{}
+	)
+
+	add := func() {
+		if v := strings.TrimSpace(string(current)); v != "" {
+			values = append(values, v)
+		}
+		current = []rune{}
+	}
+
+	for _, c := range input {
+
+		switch {
+		case escaped:
+			escaped = false
+
+		case c == '\\':
+			escaped = true
+
+		case lastQuote != rune(0):
+			if c == lastQuote {
+				lastQuote = rune(0)
+			}
+
+		case c == '`' || unicode.In(c, unicode.Quotation_Mark):
+			lastQuote = c
+
+		case c == '[' || c == '(' || c == '{':
+			depth++
+
+		case c == ']' || c == ')' || c == '}':
+			depth--
+
+			if depth < 0 {
+				return nil, fmt.Errorf("Unbalanced '%c' in %s", c, input)
+			}
+
+		case c == ',' && depth == 0:
+			add()
+			continue
+		}
+
+		current = append(current, c)
+	}
+
+	if depth != 0 || lastQuote != rune(0) {
+		return nil, fmt.Errorf("Unterminated value in %s", input)
+	}
+
+	add()
+
+	return
+}
+
+// parseList splits a list literal, such as ["a", "b"], into its elements.
+// The elements are returned as literals, so strings keep their quotes.
+func parseList(value string) ([]string, error) {
+
+	value = strings.TrimSpace(value)
+
+	if len(value) < 2 || value[0] != '[' || value[len(value)-1] != ']' {
+		return nil, fmt.Errorf("Expected a list, got %s", value)
+	}
+
+	items, err := splitValues(value[1 : len(value)-1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// formatList is the inverse of parseList.
+func formatList(items []string) string {
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// unquote returns the string value of a literal if it's quoted, or the
+// literal itself if it isn't.
+func unquote(literal string) string {
+
+	if value, err := strconv.Unquote(literal); err == nil {
+		return value
+	}
+
+	return literal
+}
+
+func builtinSort(s *scope, args []string) (string, error) {
+
+	items, err := s.evaluateList("sort", args)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Lists made up entirely of numbers are sorted numerically; anything
+	// else is sorted by its string value.
+	numeric := true
+	numbers := make(map[string]float64, len(items))
+
+	for _, item := range items {
+
+		n, err := strconv.ParseFloat(item, 64)
+
+		if err != nil {
+			numeric = false
+			break
+		}
+
+		numbers[item] = n
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+
+		if numeric {
+			return numbers[items[i]] < numbers[items[j]]
+		}
+
+		return unquote(items[i]) < unquote(items[j])
+	})
+
+	return formatList(items), nil
+}
+
+func builtinUniq(s *scope, args []string) (string, error) {
+
+	items, err := s.evaluateList("uniq", args)
+
+	if err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool, len(items))
+	result := []string{}
+
+	for _, item := range items {
+
+		if seen[unquote(item)] {
+			continue
+		}
+
+		seen[unquote(item)] = true
+		result = append(result, item)
+	}
+
+	return formatList(result), nil
+}
+
+func builtinReverse(s *scope, args []string) (string, error) {
+
+	items, err := s.evaluateList("reverse", args)
+
+	if err != nil {
+		return "", err
+	}
+
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	return formatList(items), nil
+}
+
+func builtinFilter(s *scope, args []string) (string, error) {
+
+	values, err := s.evaluateArguments("filter", args, 2)
+
+	if err != nil {
+		return "", err
+	}
+
+	items, err := parseList(values[0])
+
+	if err != nil {
+		return "", err
+	}
+
+	matcher, err := regexp.Compile(unquote(values[1]))
+
+	if err != nil {
+		return "", fmt.Errorf("Invalid filter expression %s: %s", values[1], err)
+	}
+
+	result := []string{}
+
+	for _, item := range items {
+		if matcher.MatchString(unquote(item)) {
+			result = append(result, item)
+		}
+	}
+
+	return formatList(result), nil
+}
+
+// builtinMap evaluates its second argument once for every item in the list,
+// with the item available as $item. The template isn't evaluated until then.
+// A string item keeps its quotes unless the template is itself a string, so
+// that map($names, $item) gives a list of strings and map($names, "$item-a")
+// gives a list of single strings rather than nested quotes.
+func builtinMap(s *scope, args []string) (string, error) {
+
+	if len(args) != 2 {
+		return "", fmt.Errorf("Wrong number of arguments for map (required 2, got %d)", len(args))
+	}
+
+	items, err := s.evaluateList("map", args[:1])
+
+	if err != nil {
+		return "", err
+	}
+
+	interpolated := strings.HasPrefix(strings.TrimSpace(args[1]), `"`)
+	result := make([]string, len(items))
+
+	for i, item := range items {
+
+		if interpolated {
+			item = interpolatedValue(item)
+		}
+
+		itemScope := s.clone()
+		itemScope.setArgumentVariable(builtinItemVariable, item)
+
+		value, err := itemScope.evaluateLiteral(args[1])
+
+		if err != nil {
+			return "", err
+		}
+
+		result[i] = value
+	}
+
+	return formatList(result), nil
+}
+
+func builtinEnumerate(s *scope, args []string) (string, error) {
+
+	items, err := s.evaluateList("enumerate", args)
+
+	if err != nil {
+		return "", err
+	}
+
+	result := make([]string, len(items))
+
+	for i, item := range items {
+		result[i] = formatList([]string{strconv.Itoa(i), item})
+	}
+
+	return formatList(result), nil
+}
+
+// builtinZip pairs up the items of two or more lists, which must all be the
+// same length: zip(["a", "b"], [1, 2]) gives [["a", 1], ["b", 2]].
+func builtinZip(s *scope, args []string) (string, error) {
+
+	if len(args) < 2 {
+		return "", fmt.Errorf("Wrong number of arguments for zip (required at least 2, got %d)", len(args))
+	}
+
+	lists := make([][]string, len(args))
+
+	for i, arg := range args {
+
+		items, err := s.evaluateList("zip", []string{arg})
+
+		if err != nil {
+			return "", err
+		}
+
+		if i > 0 && len(items) != len(lists[0]) {
+			return "", fmt.Errorf("Lists passed to zip must be the same length (argument %d has %d items, expected %d)", i, len(items), len(lists[0]))
+		}
+
+		lists[i] = items
+	}
+
+	result := make([]string, len(lists[0]))
+
+	for i := range result {
+
+		tuple := make([]string, len(lists))
+
+		for j, items := range lists {
+			tuple[j] = items[i]
+		}
+
+		result[i] = formatList(tuple)
+	}
+
+	return formatList(result), nil
+}
+
+// builtinTry evaluates each of its arguments in turn, returning the first one
+// that evaluates without an error.
+func builtinTry(s *scope, args []string) (string, error) {
+
+	if len(args) == 0 {
+		return "", fmt.Errorf("Wrong number of arguments for try (required at least 1, got 0)")
+	}
+
+	var err error
+
+	for _, arg := range args {
+
+		var value string
+
+		if value, err = s.evaluateLiteral(arg); err == nil {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("No argument to try could be evaluated: %s", err)
+}
+
+func builtinCan(s *scope, args []string) (string, error) {
+
+	if len(args) != 1 {
+		return "", fmt.Errorf("Wrong number of arguments for can (required 1, got %d)", len(args))
+	}
+
+	if _, err := s.evaluateLiteral(args[0]); err != nil {
+		return "false", nil
+	}
+
+	return "true", nil
+}
@@ -0,0 +1,194 @@
+package scl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AScopeCanEvaluateBuiltinFunctions(t *testing.T) {
+
+	for cycle, input := range []struct {
+		variables map[string]string
+		literal   string
+		result    string
+		err       error
+	}{
+		{
+			literal: `sort(["b", "c", "a"])`,
+			result:  `["a", "b", "c"]`,
+		},
+		{
+			literal: `sort([10, 9, 1.5])`,
+			result:  `[1.5, 9, 10]`,
+		},
+		{
+			literal: `sort([])`,
+			result:  `[]`,
+		},
+		{
+			variables: map[string]string{
+				"list": `["b", "a", "b"]`,
+			},
+			literal: `uniq($list)`,
+			result:  `["b", "a"]`,
+		},
+		{
+			variables: map[string]string{
+				"list": `["b", "a", "b"]`,
+			},
+			literal: `names = sort(uniq($list))`,
+			result:  `names = ["a", "b"]`,
+		},
+		{
+			literal: `reverse(["a", ["b", "c"], "d, e"])`,
+			result:  `["d, e", ["b", "c"], "a"]`,
+		},
+		{
+			variables: map[string]string{
+				"zones": `["eu-west-1a", "us-east-1a", "eu-west-1b"]`,
+			},
+			literal: `filter($zones, "^eu-")`,
+			result:  `["eu-west-1a", "eu-west-1b"]`,
+		},
+		{
+			variables: map[string]string{
+				"prefix": "web",
+			},
+			literal: `hosts = map([1, 2], "$prefix-$item")`,
+			result:  `hosts = ["web-1", "web-2"]`,
+		},
+		{
+			variables: map[string]string{
+				"prefix": "web",
+			},
+			literal: `hosts = map(["a", "b"], "$prefix-$item")`,
+			result:  `hosts = ["web-a", "web-b"]`,
+		},
+		{
+			literal: `names = map(["a", "b"], $item)`,
+			result:  `names = ["a", "b"]`,
+		},
+		{
+			literal: `map(filter([1, 20, 3], "^[0-9]$"), $item)`,
+			result:  `[1, 3]`,
+		},
+		{
+			literal: `map(["a"], $other)`,
+			err:     fmt.Errorf("Unknown variable '$other'"),
+		},
+		{
+			literal: `filter(["a"], "(")`,
+			err:     fmt.Errorf("Invalid filter expression \"(\": error parsing regexp: missing closing ): `(`"),
+		},
+		{
+			literal: `enumerate(["a", "b"])`,
+			result:  `[[0, "a"], [1, "b"]]`,
+		},
+		{
+			variables: map[string]string{
+				"subnets": `["10.0.1.0/24", "10.0.2.0/24"]`,
+				"zones":   `["eu-west-1a", "eu-west-1b"]`,
+			},
+			literal: `pairs = zip($subnets, $zones)`,
+			result:  `pairs = [["10.0.1.0/24", "eu-west-1a"], ["10.0.2.0/24", "eu-west-1b"]]`,
+		},
+		{
+			literal: `zip(["a"], [1], [true])`,
+			result:  `[["a", 1, true]]`,
+		},
+		{
+			literal: `zip(["a", "b"], [1])`,
+			err:     fmt.Errorf("Lists passed to zip must be the same length (argument 1 has 1 items, expected 2)"),
+		},
+		{
+			literal: `zip(["a"])`,
+			err:     fmt.Errorf("Wrong number of arguments for zip (required at least 2, got 1)"),
+		},
+		{
+			literal: `region = try($region, "eu-west-1")`,
+			result:  `region = "eu-west-1"`,
+		},
+		{
+			variables: map[string]string{
+				"region": `"us-east-1"`,
+			},
+			literal: `try($region, "eu-west-1")`,
+			result:  `"us-east-1"`,
+		},
+		{
+			literal: `try(sort("a"), $other, [])`,
+			result:  `[]`,
+		},
+		{
+			literal: `try($region, $other)`,
+			err:     fmt.Errorf("No argument to try could be evaluated: Unknown variable '$other'"),
+		},
+		{
+			variables: map[string]string{
+				"region": `"us-east-1"`,
+			},
+			literal: `has_region = can($region)`,
+			result:  `has_region = true`,
+		},
+		{
+			literal: `can(sort("a"))`,
+			result:  `false`,
+		},
+		{
+			literal: `sortable = "sort(not a call)"`,
+			result:  `sortable = "sort(not a call)"`,
+		},
+		{
+			literal: `unknown(["a"])`,
+			result:  `unknown(["a"])`,
+		},
+		{
+			literal: `sort("a")`,
+			err:     fmt.Errorf(`Expected a list, got "a"`),
+		},
+		{
+			literal: `sort([1], [2])`,
+			err:     fmt.Errorf("Wrong number of arguments for sort (required 1, got 2)"),
+		},
+		{
+			literal: `reverse($nothing)`,
+			err:     fmt.Errorf("Unknown variable '$nothing'"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		s := newScope()
+
+		for k, v := range input.variables {
+			s.setVariable(k, v)
+		}
+
+		result, err := s.evaluateLiteral(input.literal)
+
+		require.Equal(t, input.err, err)
+		require.Equal(t, input.result, result)
+	}
+}
+
+func Test_MappedListsAreValidHCL(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("map.scl", []byte(`$names = ["a", "b"]
+names = map($names, $item)
+ports = map([80, 443], $item)`))
+
+	require.Nil(t, p.Parse("map.scl"))
+	require.Equal(t, "names = [\"a\", \"b\"]\nports = [80, 443]", p.String())
+
+	var decoded struct {
+		Names []string
+		Ports []int
+	}
+
+	require.Nil(t, hcl.Decode(&decoded, p.String()))
+	require.Equal(t, []string{"a", "b"}, decoded.Names)
+	require.Equal(t, []int{80, 443}, decoded.Ports)
+}
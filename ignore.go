@@ -0,0 +1,164 @@
+package scl
+
+import (
+	"path"
+	"strings"
+)
+
+// ignoreFileName is the file, in the base path of a disk filesystem, which
+// lists the paths that wildcards never match.
+const ignoreFileName = ".sclignore"
+
+// unwalkedDirectories aren't searched by ** unless a pattern names them, as
+// they hold other people's code and can be very large.
+var unwalkedDirectories = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+}
+
+// An ignoreRule is one line of an ignore file.
+type ignoreRule struct {
+	pattern  string
+	negated  bool
+	dirOnly  bool
+	anchored bool
+}
+
+/*
+ignoreRules hold the patterns of an ignore file, which are written in the same
+syntax as a .gitignore file: a pattern containing a slash is matched against
+the whole path, relative to the ignore file, and a pattern without one against
+the name of each file and directory. A trailing slash only matches directories,
+** matches any number of directories, and a leading ! re-includes paths that an
+earlier pattern ignored. Blank lines and lines starting with # are skipped.
+*/
+type ignoreRules []ignoreRule
+
+func parseIgnoreRules(content string) (rules ignoreRules) {
+
+	for _, line := range strings.Split(content, "\n") {
+
+		line = strings.TrimRight(line, " \t\r")
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negated = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+
+	return
+}
+
+// ignored reports whether a slash-separated path, relative to the ignore file,
+// is ignored. A path in an ignored directory is always ignored, as with git.
+func (rules ignoreRules) ignored(name string, dir bool) bool {
+
+	if len(rules) == 0 {
+		return false
+	}
+
+	parts := strings.Split(path.Clean(name), "/")
+
+	for i := 1; i < len(parts); i++ {
+		if rules.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return rules.matches(name, dir)
+}
+
+// matches reports whether the last rule to match a path ignores it, without
+// considering the directories it's in.
+func (rules ignoreRules) matches(name string, dir bool) bool {
+
+	name = path.Clean(name)
+	ignored := false
+
+	for _, rule := range rules {
+
+		if rule.dirOnly && !dir {
+			continue
+		}
+
+		target := name
+
+		if !rule.anchored {
+			target = path.Base(name)
+		}
+
+		if ok, _ := matchPath(rule.pattern, target); ok {
+			ignored = !rule.negated
+		}
+	}
+
+	return ignored
+}
+
+// matchPath reports whether a slash-separated path matches a pattern, in which
+// ** matches any number of directories, including none.
+func matchPath(pattern, name string) (bool, error) {
+	return matchParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchParts(pattern, name []string) (bool, error) {
+
+	for len(pattern) > 0 {
+
+		if pattern[0] == "**" {
+
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchParts(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+
+		if !ok || err != nil {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// hasWildcards reports whether a glob pattern can match anything other than
+// itself.
+func hasWildcards(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
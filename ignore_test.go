@@ -0,0 +1,99 @@
+package scl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IgnoreRulesFollowGitignoreSyntax(t *testing.T) {
+
+	rules := parseIgnoreRules(`
+# Build output
+build/
+*.tmp
+/generated.scl
+docs/**/draft.scl
+!keep.tmp
+\#hash.scl
+`)
+
+	for cycle, input := range []struct {
+		path    string
+		dir     bool
+		ignored bool
+	}{
+		{"build", true, true},
+		{"build", false, false},
+		{"build/a.scl", false, true},
+		{"src/build/a.scl", false, true},
+		{"a.tmp", false, true},
+		{"src/a.tmp", false, true},
+		{"keep.tmp", false, false},
+		{"generated.scl", false, true},
+		{"src/generated.scl", false, false},
+		{"docs/draft.scl", false, true},
+		{"docs/a/b/draft.scl", false, true},
+		{"src/docs/draft.scl", false, false},
+		{"#hash.scl", false, true},
+		{"a.scl", false, false},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.ignored, rules.ignored(input.path, input.dir), input.path)
+	}
+}
+
+func Test_DiskGlobsSkipIgnoredAndVendoredFiles(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "scl-ignore")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{
+		"a.scl",
+		"src/b.scl",
+		"src/deep/c.scl",
+		"src/skip.scl",
+		"build/d.scl",
+		"vendor/lib/e.scl",
+		"node_modules/pkg/f.scl",
+	} {
+		require.Nil(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("a = 1"), 0644))
+	}
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, ignoreFileName), []byte("build/\nskip.scl\n"), 0644))
+
+	fs := NewDiskSystem(dir)
+
+	for cycle, input := range []struct {
+		pattern  string
+		expected []string
+	}{
+		{"**/*.scl", []string{"a.scl", "src/b.scl", "src/deep/c.scl"}},
+		{"src/**/*.scl", []string{"src/b.scl", "src/deep/c.scl"}},
+		{"src/*.scl", []string{"src/b.scl"}},
+		{"src/skip.scl", []string{"src/skip.scl"}},
+		{"vendor/**/*.scl", []string{"vendor/lib/e.scl"}},
+		{"**/node_modules/**/*.scl", []string{"node_modules/pkg/f.scl"}},
+		{"missing/**/*.scl", nil},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		matches, err := fs.Glob(input.pattern)
+		require.Nil(t, err)
+
+		var relative []string
+
+		for _, match := range matches {
+			name, err := filepath.Rel(dir, match)
+			require.Nil(t, err)
+			relative = append(relative, filepath.ToSlash(name))
+		}
+
+		require.Equal(t, input.expected, relative)
+	}
+}
@@ -0,0 +1,158 @@
+package scl
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+)
+
+/*
+RewriteIncludePaths changes the file names given to include, include_prefixed
+and instance statements in SCL source which are, or are within, one path so
+that they start with another instead, returning the new source and the number
+of file names changed. Paths are compared a directory at a time, so moving
+"lib/net" leaves "lib/network" alone.
+
+The source is tokenised rather than searched, so only literal file names are
+changed: comments, strings elsewhere and names built from variables are left
+alone, as are the prefixes given to include_prefixed and instance.
+*/
+func RewriteIncludePaths(src []byte, fileName, from, to string) ([]byte, int, error) {
+
+	from, to = path.Clean(from), path.Clean(to)
+
+	if from == "." || to == "." {
+		return nil, 0, fmt.Errorf("Can't rewrite include paths: paths must not be empty")
+	}
+
+	tree, err := newScanner(bytes.NewReader(src), fileName).scan()
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("Can't scan %s: %s", fileName, err)
+	}
+
+	lines := strings.Split(string(src), "\n")
+	tkn := newTokeniser()
+	changed := 0
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return fmt.Errorf("[%s] %s", branch, err)
+			}
+
+			if len(tokens) == 0 || tokens[0].kind == tokenCommentStart {
+				continue
+			}
+
+			if tokens[0].kind == tokenFunctionCall {
+
+				var files []token
+
+				switch tokens[0].content {
+				case builtinMixinInclude:
+					files, _ = splitIncludeArguments(tokens[1:])
+				case builtinMixinPrefixed:
+					if files, _ = splitIncludeArguments(tokens[1:]); len(files) > 0 {
+						files = files[1:]
+					}
+				case builtinMixinInstance:
+					if files, _ = splitIncludeArguments(tokens[1:]); len(files) > 1 {
+						files = files[:1]
+					}
+				}
+
+				line, n, err := rewriteIncludeLine(lines[branch.line-1], files, from, to)
+
+				if err != nil {
+					return fmt.Errorf("[%s] %s", branch, err)
+				}
+
+				lines[branch.line-1] = line
+				changed += n
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(tree); err != nil {
+		return nil, 0, err
+	}
+
+	if changed == 0 {
+		return src, 0, nil
+	}
+
+	return []byte(strings.Join(lines, "\n")), changed, nil
+}
+
+// rewriteIncludeLine replaces the quoted file names on a line which start with
+// a path. The tokens are found in order, so a name that appears more than once
+// on the line is only replaced where it's a file name.
+func rewriteIncludeLine(line string, files []token, from, to string) (string, int, error) {
+
+	changed := 0
+	offset := strings.Index(line, "(")
+
+	if offset < 0 {
+		return line, 0, nil
+	}
+
+	for _, t := range files {
+
+		if t.kind != tokenLiteral || len(t.content) < 2 || !strings.ContainsAny(t.content[:1], `"'`) {
+			continue
+		}
+
+		at := strings.Index(line[offset:], t.content)
+
+		if at < 0 {
+			return "", 0, fmt.Errorf("Can't find %s", t.content)
+		}
+
+		at += offset
+		quote := t.content[:1]
+		name := strings.Trim(t.content, quote)
+		offset = at + len(t.content)
+
+		rewritten, ok := rewriteIncludePath(name, from, to)
+
+		if !ok {
+			continue
+		}
+
+		replacement := quote + rewritten + quote
+		line = line[:at] + replacement + line[offset:]
+		offset = at + len(replacement)
+		changed++
+	}
+
+	return line, changed, nil
+}
+
+func rewriteIncludePath(name, from, to string) (string, bool) {
+
+	clean := path.Clean(name)
+
+	if clean == from {
+		return to, true
+	}
+
+	if strings.HasPrefix(clean, from+"/") {
+		return to + strings.TrimPrefix(clean, from), true
+	}
+
+	return name, false
+}
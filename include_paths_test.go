@@ -0,0 +1,44 @@
+package scl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IncludePathsCanBeRewritten(t *testing.T) {
+
+	src := `// include("vendor/old/net")
+include("vendor/old/net", "vendor/old/network", "local")
+include_prefixed("vendor/old/net", "./vendor/old/net/dns")
+instance("vendor/old/db", "vendor/old/db", $size = "large")
+
+app {
+  include("vendor/old/*.scl")
+  value = "vendor/old/net"
+}
+`
+
+	out, changed, err := RewriteIncludePaths([]byte(src), "main.scl", "vendor/old", "vendor/new")
+	require.Nil(t, err)
+	require.Equal(t, 5, changed)
+	require.Equal(t, `// include("vendor/old/net")
+include("vendor/new/net", "vendor/new/network", "local")
+include_prefixed("vendor/old/net", "vendor/new/net/dns")
+instance("vendor/new/db", "vendor/old/db", $size = "large")
+
+app {
+  include("vendor/new/*.scl")
+  value = "vendor/old/net"
+}
+`, string(out))
+
+	out, changed, err = RewriteIncludePaths([]byte(src), "main.scl", "vendor/old/net", "lib/net")
+	require.Nil(t, err)
+	require.Equal(t, 2, changed)
+	require.Contains(t, string(out), `include("lib/net", "vendor/old/network", "local")`)
+	require.Contains(t, string(out), `include_prefixed("vendor/old/net", "lib/net/dns")`)
+
+	_, _, err = RewriteIncludePaths([]byte(src), "main.scl", "", "lib")
+	require.NotNil(t, err)
+}
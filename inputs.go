@@ -0,0 +1,57 @@
+package scl
+
+import "sort"
+
+/*
+Inputs lists the external inputs a Parser read: the params set with SetParam()
+that were referenced as variables, and the files that were opened. Tools can
+check them against an allowlist to keep renders hermetic.
+*/
+type Inputs struct {
+	Params []string
+	Files  []string
+}
+
+type inputs struct {
+	params map[string]*bool
+	files  map[string]bool
+}
+
+func newInputs() inputs {
+	return inputs{
+		params: make(map[string]*bool),
+		files:  make(map[string]bool),
+	}
+}
+
+// param returns the flag shared by every copy of a param's variable, which is
+// set when the variable is read.
+func (i inputs) param(name string) *bool {
+
+	if read, ok := i.params[name]; ok {
+		return read
+	}
+
+	read := new(bool)
+	i.params[name] = read
+
+	return read
+}
+
+func (i inputs) list() (list Inputs) {
+
+	for name, read := range i.params {
+		if *read {
+			list.Params = append(list.Params, name)
+		}
+	}
+
+	for path := range i.files {
+		list.Files = append(list.Files, path)
+	}
+
+	sort.Strings(list.Params)
+	sort.Strings(list.Files)
+
+	return
+}
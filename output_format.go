@@ -0,0 +1,209 @@
+package scl
+
+import (
+	"regexp"
+	"strings"
+)
+
+var attributeMatcher = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_\-.]*|"[^"]*")\s*=\s*(.*)$`)
+
+/*
+An OutputFormat controls the layout of a Parser's HCL output. The zero value is
+the standard layout, with two spaces of indentation and no alignment or
+wrapping.
+
+IndentWidth is the number of spaces for each level of indentation, or the
+number of tabs if IndentWithTabs is set. Zero means two spaces, or one tab.
+
+AlignAttributes lines up the equals signs of consecutive attributes in the same
+block.
+
+MaxLineWidth wraps attributes with list values that are longer than it, in
+characters including the indentation, so that each item of the list is on its
+own line. Other long lines are left alone, since they can't be broken without
+changing their meaning. Zero means lines are never wrapped.
+*/
+type OutputFormat struct {
+	IndentWidth     int
+	IndentWithTabs  bool
+	AlignAttributes bool
+	MaxLineWidth    int
+}
+
+// An outputLine is a line of output at a level of indentation. Any lines after
+// the first, such as the body of a heredoc, are written as they are.
+type outputLine struct {
+	level int
+	text  string
+	rest  string
+}
+
+// apply lays out the parser's output, which is always written with the
+// standard indentation, in the format.
+func (f OutputFormat) apply(output []string) []string {
+
+	if f == (OutputFormat{}) {
+		return output
+	}
+
+	var lines []outputLine
+
+	for _, entry := range output {
+
+		first, rest := entry, ""
+
+		if i := strings.Index(entry, "\n"); i >= 0 {
+			first, rest = entry[:i], entry[i:]
+		}
+
+		text := strings.TrimLeft(first, " ")
+		line := outputLine{level: (len(first) - len(text)) / hclIndentSize, text: text, rest: rest}
+
+		lines = append(lines, f.wrap(line)...)
+	}
+
+	if f.AlignAttributes {
+		f.align(lines)
+	}
+
+	formatted := make([]string, len(lines))
+
+	for i, line := range lines {
+		formatted[i] = f.indentation(line.level) + line.text + line.rest
+	}
+
+	return formatted
+}
+
+func (f OutputFormat) indentation(level int) string {
+
+	unit := strings.Repeat(" ", hclIndentSize)
+
+	if f.IndentWithTabs {
+		unit = "\t"
+	}
+
+	if f.IndentWidth > 0 {
+		unit = strings.Repeat(unit[:1], f.IndentWidth)
+	}
+
+	return strings.Repeat(unit, level)
+}
+
+// wrap splits an attribute with a list value that's too long into one line for
+// each item of the list.
+func (f OutputFormat) wrap(line outputLine) []outputLine {
+
+	if f.MaxLineWidth <= 0 || line.rest != "" || len(f.indentation(line.level)+line.text) <= f.MaxLineWidth {
+		return []outputLine{line}
+	}
+
+	parts := attributeMatcher.FindStringSubmatch(line.text)
+
+	if parts == nil {
+		return []outputLine{line}
+	}
+
+	items, ok := listItems(strings.TrimSpace(parts[2]))
+
+	if !ok || len(items) == 0 {
+		return []outputLine{line}
+	}
+
+	lines := []outputLine{{level: line.level, text: parts[1] + " = ["}}
+
+	for _, item := range items {
+		lines = append(lines, outputLine{level: line.level + 1, text: item + ","})
+	}
+
+	return append(lines, outputLine{level: line.level, text: "]"})
+}
+
+// listItems splits a list literal into its items, or returns false if the value
+// isn't a single list.
+func listItems(value string) (items []string, ok bool) {
+
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, false
+	}
+
+	depth := 0
+	quoted := false
+	start := 1
+
+	for i := 0; i < len(value); i++ {
+
+		c := value[i]
+
+		switch {
+		case quoted:
+
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+
+		case c == '"':
+			quoted = true
+
+		case c == '[' || c == '{':
+			depth++
+
+		case c == ']' || c == '}':
+
+			depth--
+
+			// The list ends before the end of the value, as in [1] + [2]
+			if depth == 0 && i != len(value)-1 {
+				return nil, false
+			}
+
+		case c == ',' && depth == 1:
+			items = append(items, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+
+	if last := strings.TrimSpace(value[start : len(value)-1]); last != "" {
+		items = append(items, last)
+	}
+
+	return items, true
+}
+
+// align pads the names of consecutive attributes at the same level so that
+// their equals signs line up.
+func (f OutputFormat) align(lines []outputLine) {
+
+	start := 0
+
+	for start < len(lines) {
+
+		end := start
+
+		for end < len(lines) && lines[end].level == lines[start].level && lines[end].rest == "" && attributeMatcher.MatchString(lines[end].text) {
+			end++
+		}
+
+		if end == start {
+			start++
+			continue
+		}
+
+		width := 0
+
+		for _, line := range lines[start:end] {
+			if name := attributeMatcher.FindStringSubmatch(line.text)[1]; len(name) > width {
+				width = len(name)
+			}
+		}
+
+		for i := start; i < end; i++ {
+			parts := attributeMatcher.FindStringSubmatch(lines[i].text)
+			lines[i].text = parts[1] + strings.Repeat(" ", width-len(parts[1])) + " = " + parts[2]
+		}
+
+		start = end
+	}
+}
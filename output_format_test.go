@@ -0,0 +1,93 @@
+package scl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OutputCanBeFormatted(t *testing.T) {
+
+	output := []string{
+		`server "web" {`,
+		`  name = "web"`,
+		`  instance_type = "t2.micro"`,
+		`  ports = [80, 443, "8080"]`,
+		"  body = <<EOF\nkeep = this\nEOF",
+		`  nested {}`,
+		`}`,
+	}
+
+	for cycle, input := range []struct {
+		format   OutputFormat
+		expected string
+	}{
+		{
+			format:   OutputFormat{},
+			expected: strings.Join(output, "\n"),
+		},
+		{
+			format: OutputFormat{IndentWidth: 4},
+			expected: `server "web" {
+    name = "web"
+    instance_type = "t2.micro"
+    ports = [80, 443, "8080"]
+    body = <<EOF
+keep = this
+EOF
+    nested {}
+}`,
+		},
+		{
+			format: OutputFormat{IndentWithTabs: true, AlignAttributes: true},
+			expected: "server \"web\" {\n" +
+				"\tname          = \"web\"\n" +
+				"\tinstance_type = \"t2.micro\"\n" +
+				"\tports         = [80, 443, \"8080\"]\n" +
+				"\tbody = <<EOF\nkeep = this\nEOF\n" +
+				"\tnested {}\n" +
+				"}",
+		},
+		{
+			format: OutputFormat{MaxLineWidth: 20},
+			expected: `server "web" {
+  name = "web"
+  instance_type = "t2.micro"
+  ports = [
+    80,
+    443,
+    "8080",
+  ]
+  body = <<EOF
+keep = this
+EOF
+  nested {}
+}`,
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.expected, strings.Join(input.format.apply(output), "\n"))
+	}
+}
+
+func Test_ListsAreSplitIntoItems(t *testing.T) {
+
+	for cycle, input := range []struct {
+		value string
+		items []string
+		ok    bool
+	}{
+		{`[1, 2]`, []string{"1", "2"}, true},
+		{`["a,b", [1, 2], {x = 1}]`, []string{`"a,b"`, "[1, 2]", "{x = 1}"}, true},
+		{`["a\"]", "b",]`, []string{`"a\"]"`, `"b"`}, true},
+		{`[1] + [2]`, nil, false},
+		{`"[1]"`, nil, false},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		items, ok := listItems(input.value)
+		require.Equal(t, input.ok, ok)
+		require.Equal(t, input.items, items)
+	}
+}
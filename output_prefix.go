@@ -0,0 +1,59 @@
+package scl
+
+import (
+	"regexp"
+	"strings"
+)
+
+var firstLabelMatcher = regexp.MustCompile(`^(\s*)"([^"]*)"(.*)$`)
+var firstIdentifierMatcher = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_\-]*)`)
+
+// An outputPrefix is added to the names of the blocks and attributes written
+// at one level of indentation, while include_prefixed() is being parsed.
+type outputPrefix struct {
+	value  string
+	indent int
+}
+
+// nest returns the prefix for an include_prefixed() at the given indentation.
+// Prefixes at the same indentation are combined, outermost first.
+func (o outputPrefix) nest(value string, indent int) outputPrefix {
+
+	if o.value != "" && o.indent == indent {
+		value = o.value + value
+	}
+
+	return outputPrefix{value: value, indent: indent}
+}
+
+func (o outputPrefix) appliesAt(indent int) bool {
+	return o.value != "" && o.indent == indent
+}
+
+// apply adds the prefix to a statement: to the first label of a block with
+// labels, or to the name of an attribute. Blocks without labels and comments
+// are left alone.
+func (o outputPrefix) apply(statement string) string {
+
+	if strings.HasPrefix(strings.TrimSpace(statement), "#") {
+		return statement
+	}
+
+	name := firstIdentifierMatcher.FindString(statement)
+
+	if name == "" {
+		return statement
+	}
+
+	rest := statement[len(name):]
+
+	if strings.HasPrefix(strings.TrimSpace(rest), "=") {
+		return o.value + statement
+	}
+
+	if parts := firstLabelMatcher.FindStringSubmatch(rest); len(parts) > 0 {
+		return name + parts[1] + `"` + o.value + parts[2] + `"` + parts[3]
+	}
+
+	return statement
+}
@@ -0,0 +1,37 @@
+package scl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AnOutputPrefixIsAddedToNames(t *testing.T) {
+
+	prefix := outputPrefix{value: "net_"}
+
+	for cycle, input := range []struct {
+		statement string
+		expected  string
+	}{
+		{`resource "aws_vpc" "main"`, `resource "net_aws_vpc" "main"`},
+		{`server "web"`, `server "net_web"`},
+		{`wrapper`, `wrapper`},
+		{`wrapper  `, `wrapper  `},
+		{`cidr = "10.0.0.0/16"`, `net_cidr = "10.0.0.0/16"`},
+		{`# A comment`, `# A comment`},
+	} {
+		t.Logf("Cycle %d", cycle)
+		require.Equal(t, input.expected, prefix.apply(input.statement))
+	}
+}
+
+func Test_OutputPrefixesAtTheSameIndentationAreCombined(t *testing.T) {
+
+	outer := outputPrefix{}.nest("a_", 0)
+
+	require.Equal(t, outputPrefix{value: "a_b_", indent: 0}, outer.nest("b_", 0))
+	require.Equal(t, outputPrefix{value: "b_", indent: 1}, outer.nest("b_", 1))
+	require.True(t, outer.appliesAt(0))
+	require.False(t, outer.appliesAt(1))
+}
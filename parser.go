@@ -1,8 +1,13 @@
 package scl
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl"
@@ -10,10 +15,12 @@ import (
 )
 
 const (
-	builtinMixinBody    = "__body__"
-	builtinMixinInclude = "include"
-	hclIndentSize       = 2
-	noMixinParamValue   = "_"
+	builtinMixinBody     = "__body__"
+	builtinMixinInclude  = "include"
+	builtinMixinInstance = "instance"
+	builtinMixinPrefixed = "include_prefixed"
+	hclIndentSize        = 2
+	noMixinParamValue    = "_"
 )
 
 /*
@@ -33,52 +40,228 @@ the next file will be able to access it. This can become confusing quickly,
 so it's usually best to parse only one file and let it explicitly include
 and other files at the SCL level.
 
+An include can be given params as well as file names, which are visible only
+while that inclusion is parsed, so that the same file can be included more than
+once with different settings:
+
+	include("db", $size = "large")
+
+An included file's output can be nested in a block by including it in one, as
+with any other statement. To avoid collisions between blocks with the same
+names instead, include_prefixed() adds a prefix to the first label of every
+top-level block the included files write, and to the name of every top-level
+attribute. Blocks without labels are left as they are:
+
+	include_prefixed("network_", "vpc")
+
+To stamp out a file more than once, use instance() instead. Each instance is
+parsed in its own scope, so nothing it declares or assigns leaks out of it.
+Given a prefix, the names it declares are copied into the root scope with the
+prefix, so that each instance's mixins can be called separately:
+
+	instance("service", "api", $size = "large")
+	api_server("web")
+
 SCL is an auto-documenting language, and the documentation is obtained using
 the Parser's Documentation() function. Only mixins are currently documented.
 Unlike the String() function, the documentation returned for Documentation()
 only includes the nominated file.
+
+ParseContext() is the same as Parse(), but stops with an error as soon as the
+context is cancelled, which is useful for deeply recursive mixins.
+
+The Includes() function lists every file that a file includes, directly or
+through other includes, without parsing it. UnusedIncludes() lists those that
+could be removed without changing the file's output.
+
+The Coverage() function reports which mixins and includes were used by the
+files parsed so far, and how often. Statements() lists the mixins and includes
+in a file without parsing it, so that files which were never reached can be
+reported too. Similarly, Inputs() lists the params and
+files that were read, and Environment() returns the snapshot of environment
+variables the Parser was created with, if any. Warnings() lists problems that
+didn't stop parsing, such as an include that matches files whose names differ
+only by case.
+
+Files can also be added to the Parser directly with AddVirtualFile(), which is
+useful for generated helpers. Virtual files can be parsed and included like any
+other, and take precedence over files of the same name in the FileSystem.
+Watch() reports virtual files as they're added, and changes to real files if
+the FileSystem is a Watcher.
+
+Similarly, a Postprocessor set with SetPostprocessor() can replace the output
+before it's returned by String() or WriteTo(), to add headers, reformat it or
+redact values. The layout of the output, such as its indentation, is set with
+SetOutputFormat(), and is applied before the Postprocessor.
+
+A Preprocessor set with SetPreprocessor() sees the raw content of every file
+before it's parsed, and can replace it: to strip front matter, decrypt files
+kept encrypted at rest, or run a template over them, for example.
+
+A file can declare its public interface using /export directives, in which case
+only the exported names are visible to any file that includes it. The names a
+file exports are listed by the Parser's Exports() function.
+
+A file can also start with a header of metadata directives, before anything but
+comments, which is ignored when the file is parsed and returned by Metadata().
+A directive anywhere else is an error:
+
+	/description Networking mixins
+	/author Jane Smith
+	/version 1.2.0
+	/tags network, dns
 */
 type Parser interface {
 	Parse(fileName string) error
+	ParseContext(ctx context.Context, fileName string) error
 	Documentation(fileName string) (MixinDocs, error)
+	Exports(fileName string) (ExportDocs, error)
+	Metadata(fileName string) (FileMetadata, error)
+	Includes(fileName string) ([]string, error)
+	UnusedIncludes(fileName string) ([]UnusedInclude, error)
+	Coverage() CoverageBlocks
+	Statements(fileName string) (CoverageBlocks, error)
+	Inputs() Inputs
+	Environment() Environment
+	Warnings() []string
 	SetParam(name, value string)
 	AddIncludePath(name string)
+	AddWorkspaceLibrary(name, path string)
+	AddVirtualFile(name string, content []byte)
+	Watch(ctx context.Context, path string) (<-chan Event, error)
+	SetPreprocessor(fn Preprocessor)
+	SetPostprocessor(fn Postprocessor)
+	SetOutputFormat(format OutputFormat)
+	WriteTo(w io.Writer) (int64, error)
 	String() string
 }
 
 type parser struct {
-	fs           FileSystem
-	rootScope    *scope
-	output       []string
-	indent       int
-	includePaths []string
+	fs              Reader
+	virtual         *virtualFileSystem
+	rootScope       *scope
+	output          []string
+	indent          int
+	includePaths    []string
+	workspace       Workspace
+	coverage        coverage
+	inputs          inputs
+	prefix          outputPrefix
+	ctx             context.Context
+	preprocessor    Preprocessor
+	postprocessor   Postprocessor
+	format          OutputFormat
+	environment     Environment
+	warnings        []string
+	caseInsensitive bool
 }
 
+/*
+A Preprocessor transforms the raw content of a file before it's parsed. It's
+called for every file that's read, including includes and virtual files, with
+the name the file was read by.
+*/
+type Preprocessor func(path string, src []byte) ([]byte, error)
+
+/*
+A Postprocessor transforms the Parser's output before it's returned by String()
+or written by WriteTo().
+*/
+type Postprocessor func(output []byte) ([]byte, error)
+
 /*
 NewParser creates a new, standard Parser given a FileSystem. The most common FileSystem is
 the DiskFileSystem, but any will do. The parser opens all files and reads all
 includes using the FileSystem provided.
 */
 func NewParser(fs FileSystem) (Parser, error) {
-
-	p := &parser{
-		fs:        fs,
-		rootScope: newScope(),
-	}
-
-	return p, nil
+	return NewParserFromConfig(Config{FileSystem: fs})
 }
 
 func (p *parser) SetParam(name, value string) {
-	p.rootScope.setVariable(name, value)
+	p.rootScope.setParam(name, value, p.inputs.param(name))
 }
 
 func (p *parser) AddIncludePath(name string) {
 	p.includePaths = append(p.includePaths, name)
 }
 
+func (p *parser) AddWorkspaceLibrary(name, path string) {
+	p.workspace[strings.TrimSuffix(name, "/")] = path
+}
+
+func (p *parser) AddVirtualFile(name string, content []byte) {
+	p.virtual.add(name, content)
+}
+
+func (p *parser) Watch(ctx context.Context, path string) (<-chan Event, error) {
+	return p.virtual.Watch(ctx, path)
+}
+
+func (p *parser) SetPreprocessor(fn Preprocessor) {
+	p.preprocessor = fn
+}
+
+func (p *parser) Coverage() CoverageBlocks {
+	return p.coverage.blocks()
+}
+
+func (p *parser) Inputs() Inputs {
+	return p.inputs.list()
+}
+
+func (p *parser) Environment() Environment {
+	return p.environment
+}
+
+func (p *parser) Warnings() []string {
+	return append([]string{}, p.warnings...)
+}
+
+func (p *parser) SetPostprocessor(fn Postprocessor) {
+	p.postprocessor = fn
+}
+
+func (p *parser) SetOutputFormat(format OutputFormat) {
+	p.format = format
+}
+
+func (p *parser) processedOutput() ([]byte, error) {
+
+	output := []byte(strings.Join(p.format.apply(p.output), "\n"))
+
+	if p.postprocessor == nil {
+		return output, nil
+	}
+
+	return p.postprocessor(output)
+}
+
+// String returns the output, or nothing if the postprocessor fails: the
+// unprocessed output can't be returned, as the postprocessor may have been
+// there to redact it. Use WriteTo to find out why it failed.
 func (p *parser) String() string {
-	return strings.Join(p.output, "\n")
+
+	output, err := p.processedOutput()
+
+	if err != nil {
+		return ""
+	}
+
+	return string(output)
+}
+
+func (p *parser) WriteTo(w io.Writer) (int64, error) {
+
+	output, err := p.processedOutput()
+
+	if err != nil {
+		return 0, fmt.Errorf("Can't postprocess output: %s", err)
+	}
+
+	n, err := w.Write(output)
+
+	return int64(n), err
 }
 
 func (p *parser) Parse(fileName string) error {
@@ -89,6 +272,14 @@ func (p *parser) Parse(fileName string) error {
 		return err
 	}
 
+	if _, err := p.exportsFromTree(lines, newTokeniser()); err != nil {
+		return err
+	}
+
+	if _, err := p.metadataFromTree(fileName, lines); err != nil {
+		return err
+	}
+
 	if err := p.parseTree(lines, newTokeniser(), p.rootScope); err != nil {
 		return err
 	}
@@ -96,6 +287,14 @@ func (p *parser) Parse(fileName string) error {
 	return nil
 }
 
+func (p *parser) ParseContext(ctx context.Context, fileName string) error {
+
+	p.ctx = ctx
+	defer func() { p.ctx = context.Background() }()
+
+	return p.Parse(fileName)
+}
+
 func (p *parser) Documentation(fileName string) (MixinDocs, error) {
 
 	docs := MixinDocs{}
@@ -113,6 +312,105 @@ func (p *parser) Documentation(fileName string) (MixinDocs, error) {
 	return docs, nil
 }
 
+func (p *parser) Includes(fileName string) ([]string, error) {
+
+	seen := map[string]bool{fileName: true}
+
+	if err := p.includesOf(fileName, seen); err != nil {
+		return nil, err
+	}
+
+	delete(seen, fileName)
+
+	includes := make([]string, 0, len(seen))
+
+	for path := range seen {
+		includes = append(includes, path)
+	}
+
+	sort.Strings(includes)
+
+	return includes, nil
+}
+
+func (p *parser) Exports(fileName string) (ExportDocs, error) {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return ExportDocs{}, err
+	}
+
+	return p.exportsFromTree(lines, newTokeniser())
+}
+
+func (p *parser) Metadata(fileName string) (FileMetadata, error) {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return FileMetadata{File: fileName}, err
+	}
+
+	return p.metadataFromTree(fileName, lines)
+}
+
+// metadataFromTree reads the metadata directives in a file's header. A
+// directive anywhere else is an error, whether the file is being parsed or
+// only read for its metadata.
+func (p *parser) metadataFromTree(fileName string, lines scannerTree) (FileMetadata, error) {
+
+	metadata := FileMetadata{File: fileName}
+	tkn := newTokeniser()
+	header := true
+
+	var walk func(tree scannerTree, topLevel bool) error
+
+	walk = func(tree scannerTree, topLevel bool) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return p.err(branch, err.Error())
+			}
+
+			if len(tokens) == 0 {
+				continue
+			}
+
+			switch tokens[0].kind {
+
+			case tokenCommentStart, tokenCommentEnd, tokenLineComment:
+				continue
+
+			case tokenMetadata:
+
+				if !topLevel || !header {
+					return p.err(branch, "Metadata must be declared in the header of a file, before anything but comments")
+				}
+
+				if err := metadata.set(tokens[0].content, tokens[1].content); err != nil {
+					return p.err(branch, err.Error())
+				}
+
+				continue
+			}
+
+			header = false
+
+			if err := walk(branch.children, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return metadata, walk(lines, true)
+}
+
 func (p *parser) scanFile(fileName string) (lines scannerTree, err error) {
 
 	f, _, err := p.fs.ReadCloser(fileName)
@@ -123,7 +421,28 @@ func (p *parser) scanFile(fileName string) (lines scannerTree, err error) {
 
 	defer f.Close()
 
-	lines, err = newScanner(f, fileName).scan()
+	if !p.virtual.has(fileName) {
+		p.inputs.files[fileName] = true
+	}
+
+	var source io.Reader = f
+
+	if p.preprocessor != nil {
+
+		content, err := ioutil.ReadAll(f)
+
+		if err != nil {
+			return lines, fmt.Errorf("Can't read %s: %s", fileName, err)
+		}
+
+		if content, err = p.preprocessor(fileName, content); err != nil {
+			return lines, fmt.Errorf("Can't preprocess %s: %s", fileName, err)
+		}
+
+		source = bytes.NewReader(content)
+	}
+
+	lines, err = newScanner(source, fileName).scan()
 
 	if err != nil {
 		return lines, fmt.Errorf("Can't scan %s: %s", fileName, err)
@@ -151,12 +470,16 @@ func (p *parser) indentedValue(literal string) string {
 
 func (p *parser) writeLiteralToOutput(scope *scope, literal string, block bool) error {
 
-	literal, err := scope.interpolateLiteral(literal)
+	literal, err := scope.evaluateLiteral(literal)
 
 	if err != nil {
 		return err
 	}
 
+	if p.prefix.appliesAt(p.indent) {
+		literal = p.prefix.apply(literal)
+	}
+
 	line := p.indentedValue(literal)
 
 	if block {
@@ -189,6 +512,11 @@ func (p *parser) endBlock() {
 	p.output = append(p.output, p.indentedValue("}"))
 }
 
+// warn records a problem that doesn't stop parsing.
+func (p *parser) warn(branch *scannerLine, w string, args ...interface{}) {
+	p.warnings = append(p.warnings, fmt.Sprintf("[%s] %s", branch.String(), fmt.Sprintf(w, args...)))
+}
+
 func (p *parser) err(branch *scannerLine, e string, args ...interface{}) error {
 	return fmt.Errorf("[%s] %s", branch.String(), fmt.Sprintf(e, args...))
 }
@@ -197,6 +525,10 @@ func (p *parser) parseTree(tree scannerTree, tkn *tokeniser, scope *scope) error
 
 	for _, branch := range tree {
 
+		if err := p.ctx.Err(); err != nil {
+			return p.err(branch, err.Error())
+		}
+
 		tokens, err := tkn.tokenise(branch)
 
 		if err != nil {
@@ -217,7 +549,7 @@ func (p *parser) parseTree(tree scannerTree, tkn *tokeniser, scope *scope) error
 
 			case tokenVariableAssignment:
 
-				value, err := scope.interpolateLiteral(tokens[1].content)
+				value, err := scope.evaluateLiteral(tokens[1].content)
 
 				if err != nil {
 					return err
@@ -227,7 +559,7 @@ func (p *parser) parseTree(tree scannerTree, tkn *tokeniser, scope *scope) error
 
 			case tokenVariableDeclaration:
 
-				value, err := scope.interpolateLiteral(tokens[1].content)
+				value, err := scope.evaluateLiteral(tokens[1].content)
 
 				if err != nil {
 					return err
@@ -237,7 +569,7 @@ func (p *parser) parseTree(tree scannerTree, tkn *tokeniser, scope *scope) error
 
 			case tokenConditionalVariableAssignment:
 
-				value, err := scope.interpolateLiteral(tokens[1].content)
+				value, err := scope.evaluateLiteral(tokens[1].content)
 
 				if err != nil {
 					return err
@@ -252,12 +584,26 @@ func (p *parser) parseTree(tree scannerTree, tkn *tokeniser, scope *scope) error
 					return err
 				}
 
+			case tokenExport:
+
+				// An export with a value is also an assignment in the exporting file
+				if len(tokens) > 1 {
+
+					value, err := scope.evaluateLiteral(tokens[1].content)
+
+					if err != nil {
+						return p.err(branch, err.Error())
+					}
+
+					scope.setVariable(token.content, value)
+				}
+
 			case tokenFunctionCall:
 				if err := p.parseFunctionCall(branch, tkn, tokens, scope.clone()); err != nil {
 					return err
 				}
 
-			case tokenCommentStart, tokenCommentEnd, tokenLineComment:
+			case tokenCommentStart, tokenCommentEnd, tokenLineComment, tokenMetadata:
 				// Do nothing
 
 			default:
@@ -446,6 +792,7 @@ func (p *parser) parseMixinDeclaration(branch *scannerLine, tokens []token, scop
 	}
 
 	scope.setMixin(tokens[0].content, branch, arguments, defaults)
+	p.coverage.declare(CoverageMixin, tokens[0].content, branch)
 
 	return nil
 }
@@ -457,6 +804,10 @@ func (p *parser) parseFunctionCall(branch *scannerLine, tkn *tokeniser, tokens [
 		return p.parseBodyCall(branch, tkn, scope)
 	} else if tokens[0].content == builtinMixinInclude {
 		return p.parseIncludeCall(branch, tokens, scope)
+	} else if tokens[0].content == builtinMixinInstance {
+		return p.parseInstanceCall(branch, tokens, scope)
+	} else if tokens[0].content == builtinMixinPrefixed {
+		return p.parseIncludePrefixedCall(branch, tokens, scope)
 	}
 
 	// Make sure the mixin exists in the scope
@@ -466,6 +817,8 @@ func (p *parser) parseFunctionCall(branch *scannerLine, tkn *tokeniser, tokens [
 		return p.err(branch, err.Error())
 	}
 
+	p.coverage.use(CoverageMixin, tokens[0].content, mx.declaration)
+
 	args, err := p.extractValuesFromArgTokens(branch, tokens[1:], scope)
 
 	if err != nil {
@@ -482,6 +835,23 @@ func (p *parser) parseFunctionCall(branch *scannerLine, tkn *tokeniser, tokens [
 		return p.err(branch, "Wrong number of arguments for %s (required %d, got %d)", tokens[0].content, r, g)
 	}
 
+	// Mixins exported from a library can use the library's private
+	// mixins and variables, unless the caller has overridden them
+	if mx.library != nil {
+
+		for k, v := range mx.library.mixins {
+			if _, ok := scope.mixins[k]; !ok {
+				scope.mixins[k] = v
+			}
+		}
+
+		for k, v := range mx.library.variables {
+			if _, ok := scope.variables[k]; !ok {
+				scope.variables[k] = v
+			}
+		}
+	}
+
 	// Set the argument values
 	for i := 0; i < len(mx.arguments); i++ {
 		scope.setArgumentVariable(mx.arguments[i].name, args[i])
@@ -512,7 +882,39 @@ func (p *parser) parseBodyCall(branch *scannerLine, tkn *tokeniser, scope *scope
 	return p.parseTree(scope.branch.children, tkn, s)
 }
 
-func (p *parser) includeGlob(name string, branch *scannerLine) error {
+// includeGlob parses the files an include argument refers to. Files already
+// included by the same statement, perhaps through a symbolic link, are skipped.
+func (p *parser) includeGlob(name string, branch *scannerLine, params []variable, included map[string]bool) error {
+
+	paths, err := p.resolveInclude(name, branch)
+
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+
+		if real := realPath(p.fs, path); included[real] {
+			continue
+		} else {
+			included[real] = true
+		}
+
+		if err := p.include(path, params); err != nil {
+			return fmt.Errorf(err.Error())
+		}
+	}
+
+	return nil
+}
+
+/*
+resolveInclude finds the files an include statement refers to, looking in the
+workspace, the vendor directory next to the including file, the include paths
+and finally the working directory, in that order. A file matched through more
+than one path, because of a symbolic link, is only listed once.
+*/
+func (p *parser) resolveInclude(name string, branch *scannerLine) ([]string, error) {
 
 	name = strings.TrimSuffix(strings.Trim(name, `"'`), ".scl") + ".scl"
 
@@ -521,12 +923,19 @@ func (p *parser) includeGlob(name string, branch *scannerLine) error {
 
 	var paths []string
 
+	// Libraries in the workspace are always read from their working
+	// copies, so they replace the vendor and include paths entirely
+	if path, ok := p.workspace.resolve(name); ok {
+		name = path
+		vendorPath = nil
+	}
+
 	for _, ip := range vendorPath {
 
-		ipaths, err := p.fs.Glob(ip + "/" + name)
+		ipaths, err := p.globInclude(ip+"/"+name, branch)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if len(ipaths) > 0 {
@@ -538,37 +947,558 @@ func (p *parser) includeGlob(name string, branch *scannerLine) error {
 	if len(paths) == 0 {
 
 		var err error
-		paths, err = p.fs.Glob(name)
+		paths, err = p.globInclude(name, branch)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if len(paths) == 0 {
-		return fmt.Errorf("Can't read %s: no files found", name)
+		return nil, fmt.Errorf("Can't read %s: no files found", name)
 	}
 
+	seen := make(map[string]bool, len(paths))
+	unique := paths[:0:0]
+
 	for _, path := range paths {
-		if err := p.Parse(path); err != nil {
-			return fmt.Errorf(err.Error())
+		if real := realPath(p.fs, path); !seen[real] {
+			seen[real] = true
+			unique = append(unique, path)
+		}
+	}
+
+	return unique, nil
+}
+
+/*
+globInclude lists the files matching an include pattern. If the parser is case
+insensitive, names match whatever their case, and if more than one file
+matches a name in different cases, the one in the same case is used, or else
+the first, with a warning.
+*/
+func (p *parser) globInclude(pattern string, branch *scannerLine) ([]string, error) {
+
+	paths, err := glob(p.fs, pattern)
+
+	if err != nil || !p.caseInsensitive {
+		return paths, err
+	}
+
+	candidates, err := glob(p.fs, caseInsensitivePattern(pattern))
+
+	// Without wildcard support, only exact matches can be found
+	if err != nil {
+		return paths, nil
+	}
+
+	exact := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		exact[filepath.Clean(path)] = true
+	}
+
+	var names []string
+	byName := make(map[string][]string)
+
+	for _, candidate := range candidates {
+
+		name := strings.ToLower(filepath.Clean(candidate))
+
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
+		}
+
+		byName[name] = append(byName[name], candidate)
+	}
+
+	var matches []string
+
+	for _, name := range names {
+
+		chosen := byName[name][0]
+
+		for _, candidate := range byName[name] {
+			if exact[filepath.Clean(candidate)] {
+				chosen = candidate
+			}
+		}
+
+		if len(byName[name]) > 1 {
+			p.warn(branch, "%s matches files that differ only by case: %s. Using %s", pattern, strings.Join(byName[name], ", "), chosen)
+		}
+
+		matches = append(matches, chosen)
+	}
+
+	// Paths without wildcards are never ignored, but the case-insensitive
+	// pattern has wildcards, so a path matched exactly may be missing from
+	// the candidates
+	for _, path := range paths {
+		if _, ok := byName[strings.ToLower(filepath.Clean(path))]; !ok {
+			matches = append(matches, path)
+		}
+	}
+
+	return matches, nil
+}
+
+// caseInsensitivePattern replaces each letter of a glob pattern that isn't
+// already in a character class with a class of its upper and lower cases.
+func caseInsensitivePattern(pattern string) string {
+
+	var b strings.Builder
+	class := false
+
+	for i := 0; i < len(pattern); i++ {
+
+		c := pattern[i]
+
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(pattern[i])
+			continue
+		case class:
+			class = c != ']'
+		case c == '[':
+			class = true
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+			b.WriteString("[" + strings.ToLower(string(c)) + strings.ToUpper(string(c)) + "]")
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+/*
+include parses an included file. Files without any exports are parsed directly
+into the root scope, like any other file. Files with exports are parsed in a
+scope of their own, and only the names they export are copied into the root
+scope afterwards.
+
+Files included with params are also parsed in a scope of their own, so that
+the params are only visible to that inclusion. Otherwise they behave like a
+plain include: every mixin they declare is copied into the root scope, and
+keeps the params it was declared with, and so is every variable they assign.
+*/
+func (p *parser) include(fileName string, params []variable) error {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	exports, err := p.exportsFromTree(lines, newTokeniser())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.metadataFromTree(fileName, lines); err != nil {
+		return err
+	}
+
+	if len(exports) == 0 && len(params) == 0 {
+		return p.parseTree(lines, newTokeniser(), p.rootScope)
+	}
+
+	if len(exports) == 0 {
+
+		fileScope := p.rootScope.clone()
+
+		for _, param := range params {
+			fileScope.setArgumentVariable(param.name, param.value)
+		}
+
+		if err := p.parseTree(lines, newTokeniser(), fileScope); err != nil {
+			return err
+		}
+
+		for name, m := range fileScope.mixins {
+			if p.rootScope.mixins[name] != m {
+				m.library = fileScope
+				p.rootScope.mixins[name] = m
+			}
+		}
+
+		isParam := make(map[string]bool)
+
+		for _, param := range params {
+			isParam[param.name] = true
+		}
+
+		for name, v := range fileScope.variables {
+			if !isParam[name] && v != nil && p.rootScope.variables[name] != v {
+				p.rootScope.setVariable(name, v.value)
+			}
+		}
+
+		return nil
+	}
+
+	fileScope := p.rootScope.isolate()
+
+	for _, param := range params {
+		fileScope.setArgumentVariable(param.name, param.value)
+	}
+
+	if err := p.parseTree(lines, newTokeniser(), fileScope); err != nil {
+		return err
+	}
+
+	return p.copyExports(fileScope, exports, "")
+}
+
+// copyExports copies the names a file exports from the scope it was parsed in
+// into the root scope, with the given prefix. Only names the file declares
+// itself can be exported; those it inherited from the root scope can't.
+func (p *parser) copyExports(fileScope *scope, exports ExportDocs, prefix string) error {
+
+	for _, export := range exports {
+
+		found := false
+
+		if m, ok := fileScope.mixins[export.Name]; ok && export.Value == "" && p.rootScope.mixins[export.Name] != m {
+			m.library = fileScope
+			p.rootScope.mixins[prefix+export.Name] = m
+			found = true
+		}
+
+		if v, ok := fileScope.variables[export.Name]; ok && v != nil && !v.inherited && v.value != "" {
+			p.rootScope.setVariable(prefix+export.Name, v.value)
+			found = true
+		}
+
+		if !found {
+			return fmt.Errorf("[%s] Exported name %s is not declared", export.Reference, export.Name)
+		}
+	}
+
+	return nil
+}
+
+/*
+instantiate parses a file as an independent instance. Unlike include(), the
+file is always parsed in a copy of the root scope, so nothing it declares or
+assigns is visible outside it, however many times it's instantiated.
+
+Given a prefix, the names the instance exports are copied into the root scope
+with the prefix and an underscore added; files without exports export every
+mixin and variable they declare or change, other than their params.
+*/
+func (p *parser) instantiate(fileName, prefix string, params []variable) error {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	exports, err := p.exportsFromTree(lines, newTokeniser())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.metadataFromTree(fileName, lines); err != nil {
+		return err
+	}
+
+	instanceScope := p.rootScope.isolate()
+
+	for _, param := range params {
+		instanceScope.setArgumentVariable(param.name, param.value)
+	}
+
+	if err := p.parseTree(lines, newTokeniser(), instanceScope); err != nil {
+		return err
+	}
+
+	if prefix == "" {
+		return nil
+	}
+
+	prefix += "_"
+
+	if len(exports) > 0 {
+		return p.copyExports(instanceScope, exports, prefix)
+	}
+
+	isParam := make(map[string]bool)
+
+	for _, param := range params {
+		isParam[param.name] = true
+	}
+
+	for name, m := range instanceScope.mixins {
+		if p.rootScope.mixins[name] != m {
+			m.library = instanceScope
+			p.rootScope.mixins[prefix+name] = m
+		}
+	}
+
+	for name, v := range instanceScope.variables {
+
+		if isParam[name] {
+			continue
+		}
+
+		if original, ok := p.rootScope.variables[name]; !ok || original == nil || original.value != v.value {
+			p.rootScope.setVariable(prefix+name, v.value)
 		}
 	}
 
 	return nil
 }
 
+// includesOf follows the include statements in a file without parsing it,
+// adding each file found to the seen set. Include arguments are evaluated in
+// the root scope, so an include that depends on a mixin argument or a local
+// variable can't be followed and is reported as an error.
+func (p *parser) includesOf(fileName string, seen map[string]bool) error {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	tkn := newTokeniser()
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return p.err(branch, err.Error())
+			}
+
+			if len(tokens) == 0 {
+				continue
+			}
+
+			switch {
+
+			case tokens[0].kind == tokenCommentStart:
+				continue
+
+			case tokens[0].kind == tokenFunctionCall && (tokens[0].content == builtinMixinInclude || tokens[0].content == builtinMixinInstance || tokens[0].content == builtinMixinPrefixed):
+
+				fileTokens, _ := splitIncludeArguments(tokens[1:])
+
+				// The second argument to instance() and the first argument to
+				// include_prefixed() are prefixes, not files
+				if tokens[0].content == builtinMixinInstance && len(fileTokens) > 1 {
+					fileTokens = fileTokens[:1]
+				} else if tokens[0].content == builtinMixinPrefixed && len(fileTokens) > 0 {
+					fileTokens = fileTokens[1:]
+				}
+				args, err := p.extractValuesFromArgTokens(branch, fileTokens, p.rootScope)
+
+				if err != nil {
+					return p.err(branch, "Can't resolve include: %s", err.Error())
+				}
+
+				for _, arg := range args {
+
+					paths, err := p.resolveInclude(arg, branch)
+
+					if err != nil {
+						return p.err(branch, err.Error())
+					}
+
+					for _, path := range paths {
+
+						if seen[path] {
+							continue
+						}
+
+						seen[path] = true
+
+						if err := p.includesOf(path, seen); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(lines)
+}
+
+func (p *parser) exportsFromTree(tree scannerTree, tkn *tokeniser) (ExportDocs, error) {
+
+	exports := ExportDocs{}
+
+	var walk func(tree scannerTree, topLevel bool) error
+
+	walk = func(tree scannerTree, topLevel bool) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return p.err(branch, err.Error())
+			}
+
+			if len(tokens) > 0 && tokens[0].kind == tokenExport {
+
+				if !topLevel {
+					return p.err(branch, "Exports must be declared at the top level of a file")
+				}
+
+				export := ExportDoc{
+					Name:      tokens[0].content,
+					File:      branch.file,
+					Line:      branch.line,
+					Reference: branch.String(),
+				}
+
+				if len(tokens) > 1 {
+					export.Value = tokens[1].content
+				}
+
+				exports = append(exports, export)
+			}
+
+			if err := walk(branch.children, false); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(tree, true); err != nil {
+		return exports, err
+	}
+
+	return exports, nil
+}
+
 func (p *parser) parseIncludeCall(branch *scannerLine, tokens []token, scope *scope) error {
 
-	args, err := p.extractValuesFromArgTokens(branch, tokens[1:], scope)
+	fileTokens, paramTokens := splitIncludeArguments(tokens[1:])
+
+	args, err := p.extractValuesFromArgTokens(branch, fileTokens, scope)
 
 	if err != nil {
 		return p.err(branch, err.Error())
 	}
 
+	params, err := p.includeParams(branch, paramTokens, scope)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	return p.includeAll(branch, args, params)
+}
+
+func (p *parser) includeAll(branch *scannerLine, args []string, params []variable) error {
+
+	names := make([]string, len(args))
+
+	for i, v := range args {
+		names[i] = strings.Trim(v, `"'`)
+	}
+
+	p.coverage.use(CoverageInclude, strings.Join(names, ", "), branch)
+
+	included := make(map[string]bool)
+
 	for _, v := range args {
 
-		if err := p.includeGlob(v, branch); err != nil {
+		if err := p.includeGlob(v, branch, params, included); err != nil {
+			return p.err(branch, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) parseIncludePrefixedCall(branch *scannerLine, tokens []token, scope *scope) error {
+
+	fileTokens, paramTokens := splitIncludeArguments(tokens[1:])
+
+	args, err := p.extractValuesFromArgTokens(branch, fileTokens, scope)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	if len(args) < 2 {
+		return p.err(branch, "Wrong number of arguments for %s (expected a prefix and at least one file name, got %d)", builtinMixinPrefixed, len(args))
+	}
+
+	params, err := p.includeParams(branch, paramTokens, scope)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	saved := p.prefix
+	defer func() { p.prefix = saved }()
+
+	p.prefix = p.prefix.nest(strings.Trim(args[0], `"'`), p.indent)
+
+	return p.includeAll(branch, args[1:], params)
+}
+
+func (p *parser) parseInstanceCall(branch *scannerLine, tokens []token, scope *scope) error {
+
+	argTokens, paramTokens := splitIncludeArguments(tokens[1:])
+
+	args, err := p.extractValuesFromArgTokens(branch, argTokens, scope)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return p.err(branch, "Wrong number of arguments for %s (expected a file name and an optional prefix, got %d)", builtinMixinInstance, len(args))
+	}
+
+	params, err := p.includeParams(branch, paramTokens, scope)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	prefix := ""
+
+	if len(args) == 2 {
+		prefix = strings.Trim(args[1], `"'`)
+	}
+
+	p.coverage.use(CoverageInclude, strings.Trim(args[0], `"'`), branch)
+
+	paths, err := p.resolveInclude(args[0], branch)
+
+	if err != nil {
+		return p.err(branch, err.Error())
+	}
+
+	for _, path := range paths {
+		if err := p.instantiate(path, prefix, params); err != nil {
 			return p.err(branch, err.Error())
 		}
 	}
@@ -576,6 +1506,42 @@ func (p *parser) parseIncludeCall(branch *scannerLine, tokens []token, scope *sc
 	return nil
 }
 
+// splitIncludeArguments separates the file names given to include() from the
+// params, which are given as assignments: include("db", $size = "large").
+func splitIncludeArguments(tokens []token) (files []token, params []token) {
+
+	for i := 0; i < len(tokens); i++ {
+
+		if tokens[i].kind == tokenVariableAssignment && i+1 < len(tokens) {
+			params = append(params, tokens[i], tokens[i+1])
+			i++
+			continue
+		}
+
+		files = append(files, tokens[i])
+	}
+
+	return
+}
+
+func (p *parser) includeParams(branch *scannerLine, tokens []token, scope *scope) ([]variable, error) {
+
+	var params []variable
+
+	for i := 0; i < len(tokens); i += 2 {
+
+		values, err := p.extractValuesFromArgTokens(branch, tokens[i+1:i+2], scope)
+
+		if err != nil {
+			return nil, err
+		}
+
+		params = append(params, variable{name: tokens[i].content, value: values[0]})
+	}
+
+	return params, nil
+}
+
 func (p *parser) extractValuesFromArgTokens(branch *scannerLine, tokens []token, scope *scope) ([]string, error) {
 
 	var args []string
@@ -585,7 +1551,7 @@ func (p *parser) extractValuesFromArgTokens(branch *scannerLine, tokens []token,
 
 		case tokenLiteral:
 
-			value, err := scope.interpolateLiteral(v.content)
+			value, err := scope.evaluateLiteral(v.content)
 
 			if err != nil {
 				return args, err
@@ -595,7 +1561,7 @@ func (p *parser) extractValuesFromArgTokens(branch *scannerLine, tokens []token,
 
 		case tokenVariable:
 
-			value := scope.variable(v.content)
+			value := scope.readVariable(v.content)
 
 			if value == "" {
 				return args, fmt.Errorf("Variable $%s is not declared in this scope", v.content)
@@ -1,6 +1,7 @@
 package scl
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"testing"
@@ -189,6 +190,68 @@ var2 = 3`,
 			fileName: "fixtures/valid/vendor.scl",
 			hcl:      `this = "included from vendor"`,
 		},
+		{
+			fileName: "fixtures/valid/list-functions.scl",
+			hcl: `zones = ["eu-west-1a", "eu-west-1b", "eu-west-1c"]
+reversed = [10, 3, 2]
+zone_list = ["eu-west-1b", "eu-west-1a", "eu-west-1c"]`,
+		},
+		{
+			fileName: "fixtures/valid/exports.scl",
+			hcl: `instance "web" {
+  size = "t2.micro"
+  region = "eu-west-1"
+}
+default_region = "eu-west-1"`,
+		},
+		{
+			fileName: "fixtures/valid/include-params.scl",
+			hcl: `database "primary" {
+  size = "large"
+}
+database "replica" {
+  size = "small"
+}`,
+		},
+		{
+			fileName: "fixtures/invalid/include-params.scl",
+			err:      fmt.Errorf("[fixtures/invalid/include-params.scl:3] Unknown variable '$size'"),
+		},
+		{
+			fileName: "fixtures/invalid/metadata.scl",
+			err:      fmt.Errorf("[fixtures/invalid/metadata.scl:5] Metadata must be declared in the header of a file, before anything but comments"),
+		},
+		{
+			fileName: "fixtures/valid/include-prefixed.scl",
+			hcl: `database "network_primary" {
+  size = "large"
+}
+wrapper {
+  database "a_replica" {
+    size = "small"
+  }
+}`,
+		},
+		{
+			fileName: "fixtures/valid/instance.scl",
+			hcl: `server "web" {
+  size = "small"
+}
+server "api" {
+  size = "large"
+}
+default_size = "small"`,
+		},
+		{
+			fileName: "fixtures/valid/metadata.scl",
+			hcl: `database "staging" {
+  size = "small"
+}`,
+		},
+		{
+			fileName: "fixtures/invalid/instance.scl",
+			err:      fmt.Errorf("[fixtures/invalid/instance.scl:4] Mixin server not declared in this scope"),
+		},
 		{
 			fileName: "fixtures/invalid/heredoc.scl",
 			err:      fmt.Errorf("Can't scan fixtures/invalid/heredoc.scl: Heredoc 'DOC' (started line 7) not terminated"),
@@ -245,6 +308,14 @@ var2 = 3`,
 			fileName: "fixtures/invalid/error-in-include.scl",
 			err:      fmt.Errorf("[fixtures/invalid/error-in-include.scl:1] [fixtures/invalid/illegalToken.scl:1] illegal char"),
 		},
+		{
+			fileName: "fixtures/invalid/export-private.scl",
+			err:      fmt.Errorf("[fixtures/invalid/export-private.scl:3] Mixin _instance not declared in this scope"),
+		},
+		{
+			fileName: "fixtures/invalid/export-nested.scl",
+			err:      fmt.Errorf("[fixtures/invalid/export-nested.scl:2] Exports must be declared at the top level of a file"),
+		},
 	} {
 		t.Logf("Cycle %d", cycle)
 
@@ -320,6 +391,306 @@ func Test_AParserCanExtractACommentTree(t *testing.T) {
 	require.Equal(t, expected, docs)
 }
 
+func Test_AParserCanListTheExportsOfAFile(t *testing.T) {
+
+	expected := ExportDocs{
+		ExportDoc{
+			Name:      "region",
+			File:      "fixtures/valid/library/exports.scl",
+			Line:      2,
+			Reference: "fixtures/valid/library/exports.scl:2",
+			Value:     `"eu-west-1"`,
+		},
+		ExportDoc{
+			Name:      "server",
+			File:      "fixtures/valid/library/exports.scl",
+			Line:      3,
+			Reference: "fixtures/valid/library/exports.scl:3",
+		},
+	}
+
+	p := newMockParser(t)
+	exports, err := p.Exports("fixtures/valid/library/exports.scl")
+	require.Nil(t, err)
+	require.Equal(t, expected, exports)
+}
+
+func Test_AParserCanReadTheMetadataOfAFile(t *testing.T) {
+
+	for cycle, input := range []struct {
+		fileName string
+		metadata FileMetadata
+		err      error
+	}{
+		{
+			fileName: "fixtures/valid/metadata.scl",
+			metadata: FileMetadata{
+				File:        "fixtures/valid/metadata.scl",
+				Author:      "Jane Smith",
+				Description: "Databases for the staging environment",
+				Version:     "1.2.0",
+				Tags:        []string{"database", "staging", "storage"},
+			},
+		},
+		{
+			fileName: "fixtures/valid/basic.scl",
+			metadata: FileMetadata{File: "fixtures/valid/basic.scl"},
+		},
+		{
+			fileName: "fixtures/invalid/metadata.scl",
+			metadata: FileMetadata{File: "fixtures/invalid/metadata.scl"},
+			err:      fmt.Errorf("[fixtures/invalid/metadata.scl:5] Metadata must be declared in the header of a file, before anything but comments"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p := newMockParser(t)
+		metadata, err := p.Metadata(input.fileName)
+		require.Equal(t, input.err, err)
+		require.Equal(t, input.metadata, metadata)
+	}
+
+	// Metadata values can contain anything a comment would start with
+	p := newMockParser(t)
+	p.AddVirtualFile("links.scl", []byte("/description See https://example.com // for details\na = 1"))
+
+	metadata, err := p.Metadata("links.scl")
+	require.Nil(t, err)
+	require.Equal(t, "See https://example.com // for details", metadata.Description)
+}
+
+func Test_AParserCanListTheIncludesOfAFile(t *testing.T) {
+
+	for cycle, input := range []struct {
+		fileName string
+		includes []string
+		err      error
+	}{
+		{
+			fileName: "fixtures/valid/basic.scl",
+			includes: []string{},
+		},
+		{
+			fileName: "fixtures/valid/import.scl",
+			includes: []string{"fixtures/valid/basic.scl", "fixtures/valid/simple-mixin.scl"},
+		},
+		{
+			fileName: "fixtures/valid/vendor.scl",
+			includes: []string{"fixtures/valid/vendor/vendored.scl"},
+		},
+		{
+			fileName: "fixtures/valid/exports.scl",
+			includes: []string{"fixtures/valid/library/exports.scl"},
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p := newMockParser(t)
+		includes, err := p.Includes(input.fileName)
+		require.Equal(t, input.err, err)
+		require.Equal(t, input.includes, includes)
+	}
+}
+
+func Test_AParserCanPreprocessFiles(t *testing.T) {
+
+	seen := []string{}
+
+	p := newMockParser(t)
+	p.SetPreprocessor(func(path string, src []byte) ([]byte, error) {
+		seen = append(seen, path)
+		return bytes.Replace(src, []byte("simpleMixin"), []byte("preprocessedMixin"), -1), nil
+	})
+
+	require.Nil(t, p.Parse("fixtures/valid/import.scl"))
+	require.Equal(t, []string{"fixtures/valid/import.scl", "fixtures/valid/basic.scl", "fixtures/valid/simple-mixin.scl"}, seen)
+	require.Contains(t, p.String(), `output = "this is from preprocessedMixin"`)
+
+	p = newMockParser(t)
+	p.SetPreprocessor(func(path string, src []byte) ([]byte, error) {
+		return nil, fmt.Errorf("no key")
+	})
+
+	require.Equal(t, fmt.Errorf("Can't preprocess fixtures/valid/basic.scl: no key"), p.Parse("fixtures/valid/basic.scl"))
+}
+
+func Test_AParserCanPostprocessItsOutput(t *testing.T) {
+
+	p := newMockParser(t)
+	p.SetPostprocessor(func(output []byte) ([]byte, error) {
+		return append([]byte("# header\n"), output...), nil
+	})
+
+	require.Nil(t, p.Parse("fixtures/valid/decode.scl"))
+
+	expected := "# header\nvalue0 = \"1\"\nvalue1 = 1\nvalue2 = [\"a\", \"b\", \"c\"]"
+	require.Equal(t, expected, p.String())
+
+	var buf bytes.Buffer
+	n, err := p.WriteTo(&buf)
+	require.Nil(t, err)
+	require.Equal(t, int64(len(expected)), n)
+	require.Equal(t, expected, buf.String())
+
+	// A failed postprocessor never lets the unprocessed output through
+	p.SetPostprocessor(func(output []byte) ([]byte, error) {
+		return nil, fmt.Errorf("redaction failed")
+	})
+
+	require.Equal(t, "", p.String())
+
+	_, err = p.WriteTo(&buf)
+	require.Equal(t, fmt.Errorf("Can't postprocess output: redaction failed"), err)
+}
+
+func Test_AParserRecordsTheCoverageOfMixinsAndIncludes(t *testing.T) {
+
+	expected := CoverageBlocks{
+		CoverageBlock{
+			Kind:  CoverageInclude,
+			Name:  "fixtures/valid/basic, fixtures/valid/simple-mixin",
+			File:  "fixtures/valid/import.scl",
+			Line:  1,
+			Count: 1,
+		},
+		CoverageBlock{
+			Kind:  CoverageMixin,
+			Name:  "simpleMixin",
+			File:  "fixtures/valid/simple-mixin.scl",
+			Line:  1,
+			Count: 1,
+		},
+	}
+
+	p := newMockParser(t)
+	require.Nil(t, p.Parse("fixtures/valid/import.scl"))
+	require.Equal(t, expected, p.Coverage())
+
+	// Mixins that are declared but never called are recorded with no uses
+	p = newMockParser(t)
+	require.Nil(t, p.Parse("fixtures/valid/simple-mixin.scl"))
+	uncovered := expected[1]
+	uncovered.Count = 0
+	require.Equal(t, CoverageBlocks{uncovered}, p.Coverage())
+}
+
+func Test_AParserListsTheStatementsInAFileWithoutParsingIt(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("library.scl", []byte(`@outer()
+  @inner()
+    a = 1
+  include("lib/a", "lib/b")
+include_prefixed("c_", "lib/c", "lib/d", $size = "large")
+instance("lib/e", "e_")`))
+
+	statements, err := p.Statements("library.scl")
+	require.Nil(t, err)
+	require.Equal(t, CoverageBlocks{
+		{Kind: CoverageMixin, Name: "outer", File: "library.scl", Line: 1},
+		{Kind: CoverageMixin, Name: "inner", File: "library.scl", Line: 2},
+		{Kind: CoverageInclude, Name: "lib/a, lib/b", File: "library.scl", Line: 4},
+		{Kind: CoverageInclude, Name: "lib/c, lib/d", File: "library.scl", Line: 5},
+		{Kind: CoverageInclude, Name: "lib/e", File: "library.scl", Line: 6},
+	}, statements)
+	require.Equal(t, CoverageBlocks{}, p.Coverage())
+
+	// Prefixed includes and instances are listed under the names they're
+	// covered by when parsed
+	p = newMockParser(t)
+	p.AddVirtualFile("vpc.scl", []byte(`vpc "main"
+  cidr = "10.0.0.0/16"`))
+	p.AddVirtualFile("main.scl", []byte(`include_prefixed("net_", "vpc")
+instance("vpc", "other_")`))
+
+	statements, err = p.Statements("main.scl")
+	require.Nil(t, err)
+	require.Nil(t, p.Parse("main.scl"))
+
+	covered := p.Coverage()
+	require.Len(t, covered, len(statements))
+
+	for i := range statements {
+		require.Equal(t, statements[i].Name, covered[i].Name)
+		require.Equal(t, statements[i].Line, covered[i].Line)
+	}
+}
+
+func Test_AParserRecordsItsInputs(t *testing.T) {
+
+	p := newMockParser(t)
+	p.SetParam("used", `"yes"`)
+	p.SetParam("unused", `"no"`)
+	p.AddVirtualFile("generated.scl", []byte(`include("fixtures/valid/basic")
+value = $used`))
+
+	require.Nil(t, p.Parse("generated.scl"))
+	require.Equal(t, Inputs{
+		Params: []string{"used"},
+		Files:  []string{"fixtures/valid/basic.scl"},
+	}, p.Inputs())
+
+	// A param that's reassigned before it's read isn't an input
+	p = newMockParser(t)
+	p.SetParam("used", `"yes"`)
+	p.AddVirtualFile("generated.scl", []byte(`$used = "overridden"
+value = $used`))
+
+	require.Nil(t, p.Parse("generated.scl"))
+	require.Equal(t, Inputs{}, p.Inputs())
+
+	// Checking whether a param is set doesn't read it, but passing it to a
+	// mixin does
+	p = newMockParser(t)
+	p.SetParam("checked", `"yes"`)
+	p.SetParam("passed", `"yes"`)
+	p.AddVirtualFile("generated.scl", []byte(`$checked ?= "default"
+@m($value)
+  value = $value
+m($passed)`))
+
+	require.Nil(t, p.Parse("generated.scl"))
+	require.Equal(t, Inputs{Params: []string{"passed"}}, p.Inputs())
+}
+
+func Test_AFileIncludedWithParamsSharesItsVariables(t *testing.T) {
+
+	for cycle, input := range []struct {
+		main string
+		hcl  string
+		err  error
+	}{
+		{
+			main: `include("library.scl", $size = "large")
+region = $region`,
+			hcl: `region = "eu-west-1"`,
+		},
+		{
+			main: `include("library.scl")
+region = $region`,
+			hcl: `region = "eu-west-1"`,
+		},
+		{
+			main: `include("library.scl", $size = "large")
+size = $size`,
+			err: fmt.Errorf("[main.scl:2] Unknown variable '$size'"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p := newMockParser(t)
+		p.AddVirtualFile("library.scl", []byte(`$region = "eu-west-1"`))
+		p.AddVirtualFile("main.scl", []byte(input.main))
+
+		err := p.Parse("main.scl")
+		require.Equal(t, input.err, err)
+
+		if err == nil {
+			require.Equal(t, input.hcl, p.String())
+		}
+	}
+}
+
 func printCommentTree(docs MixinDocs, indentation int) {
 
 	for _, d := range docs {
@@ -333,3 +704,35 @@ func printCommentTree(docs MixinDocs, indentation int) {
 	require.Nil(t, p.Parse("fixtures/valid/callback.scl"))
 	fmt.Println(p.String())
 }*/
+
+func Test_AFileCanOnlyExportNamesItDeclares(t *testing.T) {
+
+	for cycle, input := range []struct {
+		library string
+		err     error
+	}{
+		{
+			library: "/export region\n$region = \"eu-west-1\"",
+		},
+		{
+			library: "/export region\n$other = 1",
+			err:     fmt.Errorf("[main.scl:1] [library.scl:1] Exported name region is not declared"),
+		},
+		{
+			library: "/export inherited\n$other = 1",
+			err:     fmt.Errorf("[main.scl:1] [library.scl:1] Exported name inherited is not declared"),
+		},
+		{
+			library: "/export inherited\n$inherited = \"changed\"",
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		p := newMockParser(t)
+		p.SetParam("inherited", `"root"`)
+		p.AddVirtualFile("library.scl", []byte(input.library))
+		p.AddVirtualFile("main.scl", []byte(`include("library.scl")`))
+
+		require.Equal(t, input.err, p.Parse("main.scl"))
+	}
+}
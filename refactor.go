@@ -0,0 +1,422 @@
+package scl
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var mixinNameMatcher = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+/*
+RenameMixin renames every declaration of and call to a mixin in SCL source,
+returning the new source and the number of lines changed. The source is
+tokenised rather than searched, so variables, literals and comments that
+happen to contain the name are left alone.
+
+Mixins are renamed by name alone: a file that declares its own mixin with the
+same name as one it includes will have both renamed.
+*/
+func RenameMixin(src []byte, fileName, oldName, newName string) ([]byte, int, error) {
+
+	switch oldName {
+	case builtinMixinBody, builtinMixinInclude, builtinMixinInstance, builtinMixinPrefixed:
+		return nil, 0, fmt.Errorf("Can't rename %s: it's a built-in", oldName)
+	}
+
+	if !mixinNameMatcher.MatchString(newName) {
+		return nil, 0, fmt.Errorf("Can't rename %s: %s isn't a valid mixin name", oldName, newName)
+	}
+
+	references, err := mixinReferences(src, fileName, oldName)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(references) == 0 {
+		return src, 0, nil
+	}
+
+	matcher := regexp.MustCompile(`^(\s*@?)` + regexp.QuoteMeta(oldName) + `(\s*[(:])`)
+	lines := bytes.Split(src, []byte("\n"))
+
+	for _, ref := range references {
+
+		line := lines[ref.line-1]
+
+		if !matcher.Match(line) {
+			return nil, 0, fmt.Errorf("Can't rename %s at %s", oldName, ref)
+		}
+
+		lines[ref.line-1] = matcher.ReplaceAll(line, []byte("${1}"+newName+"${2}"))
+	}
+
+	return bytes.Join(lines, []byte("\n")), len(references), nil
+}
+
+// mixinReferences finds the lines which declare or call a mixin, skipping
+// comment blocks.
+func mixinReferences(src []byte, fileName, name string) (references []*scannerLine, err error) {
+
+	lines, err := newScanner(bytes.NewReader(src), fileName).scan()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't scan %s: %s", fileName, err)
+	}
+
+	tkn := newTokeniser()
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return fmt.Errorf("[%s] %s", branch, err)
+			}
+
+			if len(tokens) == 0 || tokens[0].kind == tokenCommentStart {
+				continue
+			}
+
+			if (tokens[0].kind == tokenMixinDeclaration || tokens[0].kind == tokenFunctionCall) && tokens[0].content == name {
+				references = append(references, branch)
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(lines); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+var refactorLiteralMatcher = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|\b[0-9]+(?:\.[0-9]+)?\b`)
+var refactorKeyMatcher = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*=\s*$`)
+var refactorBlockTypeMatcher = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+/*
+ExtractMixin moves the lines from one line number to another, inclusive, into a
+new mixin with the given name, and replaces them with a call to it. The lines
+must be a complete block, or several, without any of their children left out.
+Given all, any other blocks in the source with the same shape are replaced with
+calls too. The new source is returned with the number of blocks replaced.
+
+The mixin's parameters are chosen heuristically: string and number literals
+which differ between the blocks replaced become parameters, or the labels on
+the first line if there's only one block. The mixin is declared before the
+top-level statement containing the first block replaced.
+*/
+func ExtractMixin(src []byte, fileName string, from, to int, name string, all bool) ([]byte, int, error) {
+
+	if !mixinNameMatcher.MatchString(name) {
+		return nil, 0, fmt.Errorf("Can't extract mixin: %s isn't a valid mixin name", name)
+	}
+
+	lines := strings.Split(string(src), "\n")
+
+	if from < 1 || to < from || to > len(lines) {
+		return nil, 0, fmt.Errorf("Can't extract mixin: lines %d-%d aren't in %s", from, to, fileName)
+	}
+
+	selection := refactorBlock{start: from - 1, end: to}
+
+	if !selection.complete(lines) {
+		return nil, 0, fmt.Errorf("Can't extract mixin: lines %d-%d of %s aren't a complete block", from, to, fileName)
+	}
+
+	if _, err := newScanner(strings.NewReader(string(src)), fileName).scan(); err != nil {
+		return nil, 0, fmt.Errorf("Can't scan %s: %s", fileName, err)
+	}
+
+	shape := selection.shape(lines)
+	blocks := []refactorBlock{selection}
+
+	// Find the other blocks with the same shape that don't overlap
+	for start := 0; all && start+selection.length() <= len(lines); start++ {
+
+		b := refactorBlock{start: start, end: start + selection.length()}
+
+		if b.overlapsAny(blocks) || !b.complete(lines) || b.shape(lines) != shape {
+			continue
+		}
+
+		blocks = append(blocks, b)
+	}
+
+	literals := make([][]string, len(blocks))
+
+	for i, b := range blocks {
+		literals[i] = b.literals(lines)
+	}
+
+	// Parameters are the literals which differ between blocks, or the labels
+	// on the first line of a lone block
+	var params []int
+
+	for i := range literals[0] {
+
+		differs := false
+
+		for _, l := range literals[1:] {
+			differs = differs || l[i] != literals[0][i]
+		}
+
+		if differs {
+			params = append(params, i)
+		}
+	}
+
+	if len(blocks) == 1 {
+		for i, m := range refactorLiteralMatcher.FindAllStringIndex(lines[selection.start], -1) {
+			if strings.HasPrefix(lines[selection.start][m[0]:], `"`) && !refactorKeyMatcher.MatchString(lines[selection.start][:m[0]]) {
+				params = append(params, i)
+			}
+		}
+	}
+
+	paramNames := selection.paramNames(lines, params)
+
+	// Build the mixin from the selection, swapping parameters for variables
+	isParam := make(map[int]string)
+
+	for i, p := range params {
+		isParam[p] = paramNames[i]
+	}
+
+	var signature []string
+
+	for _, n := range paramNames {
+		signature = append(signature, "$"+n)
+	}
+
+	declaration := []string{fmt.Sprintf("@%s(%s)", name, strings.Join(signature, ", "))}
+	base := indentation(lines[selection.start])
+	literal := 0
+
+	for _, line := range lines[selection.start:selection.end] {
+
+		if strings.TrimSpace(line) == "" {
+			declaration = append(declaration, "")
+			continue
+		}
+
+		line = refactorLiteralMatcher.ReplaceAllStringFunc(line, func(l string) string {
+
+			defer func() { literal++ }()
+
+			if n, ok := isParam[literal]; ok {
+				return "$" + n
+			}
+
+			return l
+		})
+
+		declaration = append(declaration, "    "+strings.TrimPrefix(line, base))
+	}
+
+	declaration = append(declaration, "")
+
+	// Replace the blocks with calls, last first so that line numbers hold
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].start > blocks[j].start
+	})
+
+	first := blocks[len(blocks)-1].start
+
+	for _, b := range blocks {
+
+		bl := b.literals(lines)
+		args := make([]string, len(params))
+
+		for i, p := range params {
+			args[i] = bl[p]
+		}
+
+		call := fmt.Sprintf("%s%s(%s)", indentation(lines[b.start]), name, strings.Join(args, ", "))
+
+		lines = append(lines[:b.start], append([]string{call}, lines[b.end:]...)...)
+	}
+
+	// Declare the mixin before the top-level statement containing the first
+	// call, and any comments directly above it
+	insert := first
+
+	for insert > 0 && indentation(lines[insert]) != "" {
+		insert--
+	}
+
+	insert = precedingComments(lines, insert)
+
+	lines = append(lines[:insert], append(declaration, lines[insert:]...)...)
+
+	return []byte(strings.Join(lines, "\n")), len(blocks), nil
+}
+
+// A refactorBlock is a range of source lines, from start up to but not
+// including end, counting from zero.
+type refactorBlock struct {
+	start int
+	end   int
+}
+
+func (b refactorBlock) length() int {
+	return b.end - b.start
+}
+
+func (b refactorBlock) overlapsAny(blocks []refactorBlock) bool {
+
+	for _, other := range blocks {
+		if b.start < other.end && other.start < b.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// complete reports whether the block starts with a statement, and contains all
+// of the children of the statements in it.
+func (b refactorBlock) complete(lines []string) bool {
+
+	first := lines[b.start]
+
+	if strings.TrimSpace(first) == "" || isRefactorComment(first) || strings.HasPrefix(strings.TrimSpace(first), "@") {
+		return false
+	}
+
+	base := len(indentation(first))
+
+	for _, line := range lines[b.start:b.end] {
+		if strings.TrimSpace(line) != "" && len(indentation(line)) < base {
+			return false
+		}
+	}
+
+	for _, line := range lines[b.end:] {
+		if strings.TrimSpace(line) != "" {
+			return len(indentation(line)) <= base
+		}
+	}
+
+	return true
+}
+
+// shape returns the block's lines relative to its indentation, with every
+// literal blanked, so that blocks which only differ in their literals have
+// the same shape.
+func (b refactorBlock) shape(lines []string) string {
+
+	base := indentation(lines[b.start])
+	shape := make([]string, 0, b.length())
+
+	for _, line := range lines[b.start:b.end] {
+		shape = append(shape, refactorLiteralMatcher.ReplaceAllString(strings.TrimPrefix(line, base), "_"))
+	}
+
+	return strings.Join(shape, "\n")
+}
+
+func (b refactorBlock) literals(lines []string) (literals []string) {
+
+	for _, line := range lines[b.start:b.end] {
+		literals = append(literals, refactorLiteralMatcher.FindAllString(line, -1)...)
+	}
+
+	return
+}
+
+// paramNames names parameters after the attribute they're assigned to, or the
+// block they label; the labels of the first line are the block's name.
+func (b refactorBlock) paramNames(lines []string, params []int) []string {
+
+	names := make([]string, 0, len(params))
+	used := make(map[string]int)
+	literal := 0
+	p := 0
+
+	for n, line := range lines[b.start:b.end] {
+
+		for _, m := range refactorLiteralMatcher.FindAllStringIndex(line, -1) {
+
+			if p < len(params) && params[p] == literal {
+
+				name := "value"
+
+				if key := refactorKeyMatcher.FindStringSubmatch(line[:m[0]]); len(key) > 1 {
+					name = key[1]
+				} else if n == 0 {
+					name = "name"
+				} else if t := refactorBlockTypeMatcher.FindStringSubmatch(line); len(t) > 1 {
+					name = t[1]
+				}
+
+				if used[name]++; used[name] > 1 {
+					name = fmt.Sprintf("%s%d", name, used[name])
+				}
+
+				names = append(names, name)
+				p++
+			}
+
+			literal++
+		}
+	}
+
+	return names
+}
+
+func isRefactorComment(line string) bool {
+
+	trimmed := strings.TrimSpace(line)
+
+	return strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "#")
+}
+
+// precedingComments returns the first line of the comments directly above a
+// line, or the line itself if there are none.
+func precedingComments(lines []string, line int) int {
+
+	for line > 0 {
+
+		previous := strings.TrimSpace(lines[line-1])
+
+		switch {
+		case previous == "*/":
+
+			start := line - 1
+
+			for start > 0 && !strings.HasPrefix(strings.TrimSpace(lines[start]), "/*") {
+				start--
+			}
+
+			line = start
+
+		case strings.HasPrefix(previous, "//"), strings.HasPrefix(previous, "#"):
+			line--
+
+		default:
+			return line
+		}
+	}
+
+	return line
+}
+
+// indentation returns the whitespace at the start of a line.
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,438 @@
+package scl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+A MixinBody is the source of a mixin's declaration, as needed to inline it.
+Lines holds the body without its indentation; Defaults holds the default of
+each argument, or an empty string if it's required.
+*/
+type MixinBody struct {
+	Name      string
+	File      string
+	Line      int
+	Arguments []string
+	Defaults  []string
+	Lines     []string
+}
+
+/*
+FindMixin returns the body of a mixin declared in SCL source, or nil if the
+source doesn't declare it. Only simple mixins can be inlined, so it's an error
+if the mixin declares mixins or variables of its own, or calls itself.
+*/
+func FindMixin(src []byte, fileName, name string) (*MixinBody, error) {
+
+	references, err := mixinReferences(src, fileName, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		declaration *scannerLine
+		tokens      []token
+	)
+
+	for _, ref := range references {
+
+		t, err := newTokeniser().tokenise(ref)
+
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %s", ref, err)
+		}
+
+		if t[0].kind != tokenMixinDeclaration {
+			continue
+		}
+
+		if declaration != nil {
+			return nil, fmt.Errorf("Can't inline %s: it's declared at %s and %s", name, declaration, ref)
+		}
+
+		declaration, tokens = ref, t
+	}
+
+	if declaration == nil {
+		return nil, nil
+	}
+
+	body := &MixinBody{Name: name, File: declaration.file, Line: declaration.line}
+
+	for i := 1; i < len(tokens); i++ {
+
+		switch tokens[i].kind {
+
+		case tokenVariable:
+			body.Arguments = append(body.Arguments, tokens[i].content)
+			body.Defaults = append(body.Defaults, "")
+
+		case tokenVariableAssignment:
+			body.Arguments = append(body.Arguments, tokens[i].content)
+			body.Defaults = append(body.Defaults, tokens[i+1].content)
+			i++
+
+		default:
+			return nil, fmt.Errorf("Can't inline %s: unexpected argument %s at %s", name, tokens[i].content, declaration)
+		}
+	}
+
+	if err := checkInlinable(body, declaration.children, newTokeniser()); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(src), "\n")
+	end := blockEnd(lines, declaration.line-1)
+	base := ""
+
+	for _, line := range lines[declaration.line:end] {
+		if strings.TrimSpace(line) != "" {
+			base = indentation(line)
+			break
+		}
+	}
+
+	for _, line := range lines[declaration.line:end] {
+		body.Lines = append(body.Lines, strings.TrimPrefix(line, base))
+	}
+
+	return body, nil
+}
+
+/*
+InlineMixin replaces every call to a mixin in SCL source with the mixin's body,
+substituting the call's arguments for the mixin's and the call's children for
+any __body__() call, and removes the mixin's declaration if the source has
+one. It returns the new source and the number of calls replaced.
+*/
+func InlineMixin(src []byte, fileName string, body *MixinBody) ([]byte, int, error) {
+
+	references, err := mixinReferences(src, fileName, body.Name)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(references) == 0 {
+		return src, 0, nil
+	}
+
+	i := inliner{
+		fileName:     fileName,
+		body:         body,
+		lines:        strings.Split(string(src), "\n"),
+		declarations: make(map[int]bool),
+		calls:        make(map[int][]token),
+	}
+
+	for _, ref := range references {
+
+		tokens, err := newTokeniser().tokenise(ref)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("[%s] %s", ref, err)
+		}
+
+		if tokens[0].kind == tokenMixinDeclaration {
+			i.declarations[ref.line-1] = true
+		} else {
+			i.calls[ref.line-1] = tokens
+		}
+	}
+
+	out, err := i.inline(0, len(i.lines))
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return []byte(strings.Join(out, "\n")), i.inlined, nil
+}
+
+type inliner struct {
+	fileName     string
+	body         *MixinBody
+	lines        []string
+	declarations map[int]bool
+	calls        map[int][]token
+	inlined      int
+}
+
+// inline returns the lines from start to end with the calls among them
+// replaced, including calls nested in the bodies of other calls.
+func (i *inliner) inline(start, end int) (out []string, err error) {
+
+	for n := start; n < end; {
+
+		switch {
+
+		case i.declarations[n]:
+
+			// Drop the declaration, its documentation and the blank lines
+			// after it
+			out = out[:precedingComments(out, len(out))]
+			n = blockEnd(i.lines, n)
+
+			for n < end && strings.TrimSpace(i.lines[n]) == "" {
+				n++
+			}
+
+		case i.calls[n] != nil:
+
+			childEnd := blockEnd(i.lines, n)
+			children, err := i.inline(n+1, childEnd)
+
+			if err != nil {
+				return nil, err
+			}
+
+			expanded, err := i.expand(n, children)
+
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, expanded...)
+			n = childEnd
+			i.inlined++
+
+		default:
+			out = append(out, i.lines[n])
+			n++
+		}
+	}
+
+	return out, nil
+}
+
+func (i *inliner) expand(n int, children []string) ([]string, error) {
+
+	tokens := i.calls[n]
+	reference := fmt.Sprintf("%s:%d", i.fileName, n+1)
+	args := make(map[string]string)
+
+	for a, t := range tokens[1:] {
+
+		if a >= len(i.body.Arguments) {
+			return nil, fmt.Errorf("Can't inline the call at %s: too many arguments", reference)
+		}
+
+		switch t.kind {
+		case tokenLiteral:
+			args[i.body.Arguments[a]] = t.content
+		case tokenVariable:
+			args[i.body.Arguments[a]] = "$" + t.content
+		default:
+			return nil, fmt.Errorf("Can't inline the call at %s: unexpected argument %s", reference, t.content)
+		}
+	}
+
+	for a, name := range i.body.Arguments {
+		if _, ok := args[name]; !ok {
+
+			if i.body.Defaults[a] == "" {
+				return nil, fmt.Errorf("Can't inline the call at %s: no value for $%s", reference, name)
+			}
+
+			args[name] = i.body.Defaults[a]
+		}
+	}
+
+	// Children keep their indentation relative to each other
+	childBase := ""
+
+	for _, line := range children {
+		if strings.TrimSpace(line) != "" {
+			childBase = indentation(line)
+			break
+		}
+	}
+
+	callIndent := indentation(i.lines[n])
+
+	var out []string
+
+	for _, line := range i.body.Lines {
+
+		if strings.TrimSpace(line) == "" {
+			out = append(out, "")
+			continue
+		}
+
+		if strings.TrimSpace(line) == builtinMixinBody+"()" {
+
+			for _, child := range children {
+				if strings.TrimSpace(child) == "" {
+					out = append(out, "")
+				} else {
+					out = append(out, callIndent+indentation(line)+strings.TrimPrefix(child, childBase))
+				}
+			}
+
+			continue
+		}
+
+		out = append(out, callIndent+substituteArguments(line, args))
+	}
+
+	return out, nil
+}
+
+// substituteArguments replaces references to a mixin's arguments with their
+// values.
+func substituteArguments(line string, args map[string]string) string {
+
+	var out bytes.Buffer
+
+	inString := false
+
+	for n := 0; n < len(line); n++ {
+
+		c := line[n]
+
+		switch {
+
+		case c == '\\' && n+1 < len(line):
+			out.WriteByte(c)
+			out.WriteByte(line[n+1])
+			n++
+			continue
+
+		case c == '"':
+			inString = !inString
+
+		case c == '$' && n+1 < len(line) && line[n+1] == '$':
+			out.WriteString("$$")
+			n++
+			continue
+
+		case c == '$':
+
+			braced := n+1 < len(line) && line[n+1] == '{'
+			nameStart := n + 1
+
+			if braced {
+				nameStart++
+			}
+
+			nameEnd := nameStart
+
+			for nameEnd < len(line) && isVariableByte(line[nameEnd]) {
+				nameEnd++
+			}
+
+			value, ok := args[line[nameStart:nameEnd]]
+
+			if braced && (nameEnd >= len(line) || line[nameEnd] != '}') {
+				ok = false
+			}
+
+			if !ok {
+				break
+			}
+
+			if inString {
+				value = interpolatedValue(value)
+			}
+
+			out.WriteString(value)
+			n = nameEnd
+
+			if !braced {
+				n--
+			}
+
+			continue
+		}
+
+		out.WriteByte(c)
+	}
+
+	return out.String()
+}
+
+// interpolatedValue returns the form of an argument to substitute inside a
+// string. String literals lose their quotes, so the result is a single string;
+// variables are braced, so that the rest of the string can't run into their
+// names.
+func interpolatedValue(value string) string {
+
+	if strings.HasPrefix(value, "$") {
+		return "${" + value[1:] + "}"
+	}
+
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+func isVariableByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// checkInlinable rejects mixin bodies that can't be copied to their call sites
+// without changing what they do.
+func checkInlinable(body *MixinBody, tree scannerTree, tkn *tokeniser) error {
+
+	for _, branch := range tree {
+
+		tokens, err := tkn.tokenise(branch)
+
+		if err != nil {
+			return fmt.Errorf("[%s] %s", branch, err)
+		}
+
+		if len(tokens) == 0 || tokens[0].kind == tokenCommentStart {
+			continue
+		}
+
+		switch tokens[0].kind {
+
+		case tokenMixinDeclaration:
+			return fmt.Errorf("Can't inline %s: it declares the mixin %s at %s", body.Name, tokens[0].content, branch)
+
+		case tokenVariableAssignment, tokenVariableDeclaration, tokenConditionalVariableAssignment:
+			return fmt.Errorf("Can't inline %s: it assigns $%s at %s", body.Name, tokens[0].content, branch)
+
+		case tokenFunctionCall:
+			if tokens[0].content == body.Name {
+				return fmt.Errorf("Can't inline %s: it calls itself at %s", body.Name, branch)
+			}
+		}
+
+		if err := checkInlinable(body, branch.children, tkn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// blockEnd returns the index of the first line after the statement on the
+// given line and its children.
+func blockEnd(lines []string, n int) int {
+
+	base := len(indentation(lines[n]))
+	end := n + 1
+
+	for m := n + 1; m < len(lines); m++ {
+
+		if strings.TrimSpace(lines[m]) == "" {
+			continue
+		}
+
+		if len(indentation(lines[m])) <= base {
+			break
+		}
+
+		end = m + 1
+	}
+
+	return end
+}
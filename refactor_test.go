@@ -0,0 +1,285 @@
+package scl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/hcl"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AMixinCanBeRenamed(t *testing.T) {
+
+	for cycle, input := range []struct {
+		src      string
+		expected string
+		renamed  int
+		err      error
+	}{
+		{
+			src: `/*
+  server() is documented here, and isn't renamed
+*/
+@server($name)
+    server $name
+        __body__()
+
+server("web")
+server ("api")
+server:
+    $server = "server(1)"
+`,
+			expected: `/*
+  server() is documented here, and isn't renamed
+*/
+@host($name)
+    server $name
+        __body__()
+
+host("web")
+host ("api")
+host:
+    $server = "server(1)"
+`,
+			renamed: 4,
+		},
+		{
+			src:      "other()\n",
+			expected: "other()\n",
+		},
+		{
+			src: "server()\n",
+			err: fmt.Errorf("Can't rename server: 1st isn't a valid mixin name"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		newName := "host"
+
+		if input.err != nil {
+			newName = "1st"
+		}
+
+		out, renamed, err := RenameMixin([]byte(input.src), "test.scl", "server", newName)
+
+		require.Equal(t, input.err, err)
+
+		if err == nil {
+			require.Equal(t, input.expected, string(out))
+			require.Equal(t, input.renamed, renamed)
+		}
+	}
+
+	_, _, err := RenameMixin([]byte("include(\"a\")\n"), "test.scl", "include", "load")
+	require.Equal(t, fmt.Errorf("Can't rename include: it's a built-in"), err)
+}
+
+func Test_AMixinCanBeExtracted(t *testing.T) {
+
+	src := `// Servers
+server "web"
+    size = "small"
+    disk "root"
+        gb = 10
+
+server "api"
+    size = "large"
+    disk "root"
+        gb = 20
+
+database "main"
+    size = "small"
+`
+
+	for cycle, input := range []struct {
+		from, to int
+		all      bool
+		expected string
+		replaced int
+		err      error
+	}{
+		{
+			from: 7,
+			to:   10,
+			expected: `// Servers
+server "web"
+    size = "small"
+    disk "root"
+        gb = 10
+
+@web_server($name)
+    server $name
+        size = "large"
+        disk "root"
+            gb = 20
+
+web_server("api")
+
+database "main"
+    size = "small"
+`,
+			replaced: 1,
+		},
+		{
+			from: 7,
+			to:   10,
+			all:  true,
+			expected: `@web_server($name, $size, $gb)
+    server $name
+        size = $size
+        disk "root"
+            gb = $gb
+
+// Servers
+web_server("web", "small", 10)
+
+web_server("api", "large", 20)
+
+database "main"
+    size = "small"
+`,
+			replaced: 2,
+		},
+		{
+			from: 12,
+			to:   13,
+			all:  true,
+			expected: `// Servers
+server "web"
+    size = "small"
+    disk "root"
+        gb = 10
+
+server "api"
+    size = "large"
+    disk "root"
+        gb = 20
+
+@web_server($name)
+    database $name
+        size = "small"
+
+web_server("main")
+`,
+			replaced: 1,
+		},
+		{
+			from: 2,
+			to:   3,
+			err:  fmt.Errorf("Can't extract mixin: lines 2-3 of test.scl aren't a complete block"),
+		},
+		{
+			from: 12,
+			to:   20,
+			err:  fmt.Errorf("Can't extract mixin: lines 12-20 aren't in test.scl"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		out, replaced, err := ExtractMixin([]byte(src), "test.scl", input.from, input.to, "web_server", input.all)
+
+		require.Equal(t, input.err, err)
+
+		if err == nil {
+			require.Equal(t, input.expected, string(out))
+			require.Equal(t, input.replaced, replaced)
+			requireSameOutput(t, src, string(out))
+		}
+	}
+}
+
+// requireSameOutput checks that refactored source still produces the same HCL.
+func requireSameOutput(t *testing.T, before, after string) {
+
+	output := func(src string) string {
+		p := newMockParser(t)
+		p.AddVirtualFile("test.scl", []byte(src))
+		require.Nil(t, p.Parse("test.scl"))
+		return p.String()
+	}
+
+	require.Equal(t, output(before), output(after))
+}
+
+// requireValidOutput checks that refactored source produces HCL that can be
+// decoded.
+func requireValidOutput(t *testing.T, src string) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("test.scl", []byte(src))
+	require.Nil(t, p.Parse("test.scl"))
+
+	var v interface{}
+	require.Nil(t, hcl.Decode(&v, p.String()))
+}
+
+func Test_AMixinCanBeInlined(t *testing.T) {
+
+	for cycle, input := range []struct {
+		src      string
+		expected string
+		inlined  int
+		err      error
+	}{
+		{
+			src: `// Documents server
+@server($name, $size = "small")
+    server $name
+        size = $size
+        label = "${size}server"
+        __body__()
+
+$api = "api"
+
+server("web")
+    extra = true
+server($api, "large")
+`,
+			expected: `$api = "api"
+
+server "web"
+    size = "small"
+    label = "smallserver"
+    extra = true
+server $api
+    size = "large"
+    label = "largeserver"
+`,
+			inlined: 2,
+		},
+		{
+			src: `@server($name)
+    server $name
+
+server()
+`,
+			err: fmt.Errorf("Can't inline the call at test.scl:4: no value for $name"),
+		},
+		{
+			src: `@server($name)
+    $label = $name
+    server $label
+`,
+			err: fmt.Errorf("Can't inline server: it assigns $label at test.scl:2"),
+		},
+	} {
+		t.Logf("Cycle %d", cycle)
+
+		body, err := FindMixin([]byte(input.src), "test.scl", "server")
+
+		if err == nil {
+			var out []byte
+			var inlined int
+
+			out, inlined, err = InlineMixin([]byte(input.src), "test.scl", body)
+
+			if err == nil {
+				require.Equal(t, input.expected, string(out))
+				require.Equal(t, input.inlined, inlined)
+				requireValidOutput(t, string(out))
+			}
+		}
+
+		require.Equal(t, input.err, err)
+	}
+}
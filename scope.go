@@ -8,12 +8,20 @@ import (
 type variable struct {
 	name  string
 	value string
+
+	// read is set when the variable is read, if it's a param
+	read *bool
+
+	// inherited is set on the copies made by isolate, until the variable is
+	// assigned in the new scope
+	inherited bool
 }
 
 type mixin struct {
 	declaration *scannerLine
 	arguments   []variable
 	defaults    []string
+	library     *scope
 }
 
 type scope struct {
@@ -32,7 +40,7 @@ func newScope() *scope {
 }
 
 func (s *scope) setArgumentVariable(name, value string) {
-	s.variables[name] = &variable{name, value}
+	s.variables[name] = &variable{name: name, value: value}
 }
 
 func (s *scope) setVariable(name, value string) {
@@ -40,12 +48,20 @@ func (s *scope) setVariable(name, value string) {
 	v, ok := s.variables[name]
 
 	if !ok || v == nil {
-		s.variables[name] = &variable{name, value}
+		s.variables[name] = &variable{name: name, value: value}
 	} else {
 		s.variables[name].value = value
+		s.variables[name].read = nil
+		s.variables[name].inherited = false
 	}
 }
 
+// setParam sets a variable whose reads are recorded in the given flag.
+func (s *scope) setParam(name, value string, read *bool) {
+	s.setVariable(name, value)
+	s.variables[name].read = read
+}
+
 func (s *scope) variable(name string) string {
 
 	value, ok := s.variables[name]
@@ -54,7 +70,24 @@ func (s *scope) variable(name string) string {
 		return ""
 	}
 
-	return s.variables[name].value
+	return value.value
+}
+
+// readVariable looks up a variable whose value is being used, recording the
+// read if it's a param. Checks for whether a variable is set don't count.
+func (s *scope) readVariable(name string) string {
+
+	value, ok := s.variables[name]
+
+	if !ok || value == nil {
+		return ""
+	}
+
+	if value.read != nil {
+		*value.read = true
+	}
+
+	return value.value
 }
 
 func (s *scope) setMixin(name string, declaration *scannerLine, argumentTokens []token, defaults []string) {
@@ -187,7 +220,7 @@ func (s *scope) interpolateLiteral(literal string) (outp string, err error) {
 				variableIsBraceEscaped = false
 
 				// The variable is complete; look up its value
-				if replacement := s.variable(string(variable)); replacement != "" {
+				if replacement := s.readVariable(string(variable)); replacement != "" {
 					result = append(result, []byte(replacement)...)
 
 					if writeOutput {
@@ -242,7 +275,7 @@ func (s *scope) interpolateLiteral(literal string) (outp string, err error) {
 			if variableIsBraceEscaped {
 				unfinishedVariable(variable)
 				return
-			} else if replacement := s.variable(string(variable)); replacement != "" {
+			} else if replacement := s.readVariable(string(variable)); replacement != "" {
 				result = append(result, []byte(replacement)...)
 			} else {
 				unknownVariable(variable)
@@ -275,3 +308,20 @@ func (s *scope) clone() *scope {
 
 	return s2
 }
+
+// isolate creates a new root scope with copies of the variables in this scope,
+// so that assignments made in the new scope don't leak back into this one.
+func (s *scope) isolate() *scope {
+
+	s2 := newScope()
+
+	for k, v := range s.variables {
+		s2.variables[k] = &variable{name: v.name, value: v.value, read: v.read, inherited: true}
+	}
+
+	for k, v := range s.mixins {
+		s2.mixins[k] = v
+	}
+
+	return s2
+}
@@ -14,6 +14,8 @@ const (
 	tokenConditionalVariableAssignment
 	tokenCommentStart
 	tokenCommentEnd
+	tokenExport
+	tokenMetadata
 )
 
 var tokenKindsByString = map[tokenKind]string{
@@ -26,6 +28,8 @@ var tokenKindsByString = map[tokenKind]string{
 	tokenLiteral:                       "literal",
 	tokenCommentStart:                  "comment start",
 	tokenCommentEnd:                    "comment end",
+	tokenExport:                        "export",
+	tokenMetadata:                      "metadata",
 }
 
 type token struct {
@@ -17,6 +17,8 @@ var conditionalVariableMatcher = regexp.MustCompile(`^\$([a-zA-Z_0-9]+)\s*\?=\s*
 var docblockStartMatcher = regexp.MustCompile(`^/\*$`)
 var docblockEndMatcher = regexp.MustCompile(`^\*\/$`)
 var heredocMatcher = regexp.MustCompile(`<<([a-zA-Z]+)\s*$`)
+var exportMatcher = regexp.MustCompile(`^/export\s+([a-zA-Z_][a-zA-Z0-9_]*)(\s*=\s*(.+))?$`)
+var metadataMatcher = regexp.MustCompile(`^/(author|description|version|tags)\s+(.+)$`)
 
 type tokeniser struct {
 	accruedComment []string
@@ -73,6 +75,12 @@ func (t *tokeniser) stripComments(l *scannerLine) string {
 
 func (t *tokeniser) tokenise(l *scannerLine) (tokens []token, err error) {
 
+	// Metadata values are free text, which may contain // in URLs, so they're
+	// matched before comments are removed
+	if raw := strings.TrimSpace(string(l.content)); metadataMatcher.MatchString(raw) {
+		return t.tokeniseMetadata(l, lineContent(raw))
+	}
+
 	// Remove comments
 	content := t.stripComments(l)
 
@@ -95,6 +103,10 @@ func (t *tokeniser) tokenise(l *scannerLine) (tokens []token, err error) {
 		return t.tokeniseCommentEnd(l, lineContent(content))
 	}
 
+	if exportMatcher.MatchString(content) {
+		return t.tokeniseExport(l, lineContent(content))
+	}
+
 	// Mixin declarations start with a @
 	if content[0] == '@' {
 		return t.tokeniseMixinDeclaration(l, lineContent(content))
@@ -136,6 +148,39 @@ func (t *tokeniser) tokeniseCommentEnd(l *scannerLine, content lineContent) (tok
 	return
 }
 
+func (t *tokeniser) tokeniseExport(l *scannerLine, content lineContent) (tokens []token, err error) {
+
+	parts := exportMatcher.FindStringSubmatch(string(content))
+
+	if len(parts) == 0 {
+		return tokens, fmt.Errorf("Failed to parse export")
+	}
+
+	tokens = append(tokens, token{kind: tokenExport, content: parts[1], line: l})
+
+	if parts[3] != "" {
+		tokens = append(tokens, token{kind: tokenLiteral, content: parts[3], line: l})
+	}
+
+	return
+}
+
+func (t *tokeniser) tokeniseMetadata(l *scannerLine, content lineContent) (tokens []token, err error) {
+
+	parts := metadataMatcher.FindStringSubmatch(string(content))
+
+	if len(parts) == 0 {
+		return tokens, fmt.Errorf("Failed to parse metadata")
+	}
+
+	tokens = append(tokens,
+		token{kind: tokenMetadata, content: parts[1], line: l},
+		token{kind: tokenLiteral, content: strings.TrimSpace(parts[2]), line: l},
+	)
+
+	return
+}
+
 func (t *tokeniser) tokeniseFunction(l *scannerLine, input string) (name string, tokens []token, err error) {
 
 	parts := functionMatcher.FindStringSubmatch(input)
@@ -264,6 +264,8 @@ func Test_ATokeniserCanReadTokensFromAValidScannerLine(t *testing.T) {
 	var functionCallLine1 = newLine("test.scl", 1, 0, `fn($a,"123")`)
 	var shortFunctionCallLine1 = newLine("test.scl", 1, 0, `fn:`)
 	var assignmentLine = newLine("test.scl", 1, 0, `$a = "123"`)
+	var exportLine1 = newLine("test.scl", 1, 0, `/export a`)
+	var exportLine2 = newLine("test.scl", 1, 0, `/export a = "123"`)
 
 	for cycle, input := range []struct {
 		line   *scannerLine
@@ -399,6 +401,31 @@ func Test_ATokeniserCanReadTokensFromAValidScannerLine(t *testing.T) {
 				},
 			},
 		},
+		{
+			line: exportLine1,
+			tokens: []token{
+				token{
+					kind:    tokenExport,
+					content: "a",
+					line:    exportLine1,
+				},
+			},
+		},
+		{
+			line: exportLine2,
+			tokens: []token{
+				token{
+					kind:    tokenExport,
+					content: "a",
+					line:    exportLine2,
+				},
+				{
+					kind:    tokenLiteral,
+					content: `"123"`,
+					line:    exportLine2,
+				},
+			},
+		},
 	} {
 		t.Logf("Cycle %d", cycle)
 
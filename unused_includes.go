@@ -0,0 +1,434 @@
+package scl
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var variableReferenceMatcher = regexp.MustCompile(`\$\{?([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+/*
+An UnusedInclude is an argument to an include statement which provides no
+mixins or variables that are used, and writes no output of its own.
+*/
+type UnusedInclude struct {
+	File string
+	Line int
+	Name string
+}
+
+/*
+UnusedIncludes lists the arguments to the include statements in a file that
+could be removed without changing its output. An included file is used if it
+writes output, or if a mixin or variable it provides is referenced by the file
+or by any other file it includes. A file with /export directives provides the
+names it exports; any other file provides every mixin and variable declared at
+its top level, including those of the files it includes in turn.
+
+Includes whose arguments can't be evaluated without parsing the file, because
+they use local variables for example, are never reported.
+*/
+func (p *parser) UnusedIncludes(fileName string) ([]UnusedInclude, error) {
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := p.includeStatements(lines)
+
+	if err != nil {
+		return nil, err
+	}
+
+	everything, err := p.Includes(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string]map[string]bool)
+
+	for _, path := range append([]string{fileName}, everything...) {
+
+		tree, err := p.scanFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if references[path], err = referencedNames(tree); err != nil {
+			return nil, err
+		}
+	}
+
+	var unused []UnusedInclude
+
+	for _, statement := range statements {
+
+		for _, name := range statement.names {
+
+			paths, err := p.resolveInclude(name, statement.branch)
+
+			if err != nil {
+				return nil, p.err(statement.branch, err.Error())
+			}
+
+			provides := provision{names: make(map[string]bool), files: make(map[string]bool)}
+
+			for _, path := range paths {
+				if err := p.provides(path, &provides); err != nil {
+					return nil, err
+				}
+			}
+
+			if provides.output || provides.used(references) {
+				continue
+			}
+
+			unused = append(unused, UnusedInclude{
+				File: statement.branch.file,
+				Line: statement.branch.line,
+				Name: strings.Trim(name, `"'`),
+			})
+		}
+	}
+
+	return unused, nil
+}
+
+type includeStatement struct {
+	branch *scannerLine
+	names  []string
+}
+
+// includeStatements finds the include() calls in a file whose file names can
+// be evaluated in the root scope.
+func (p *parser) includeStatements(lines scannerTree) (statements []includeStatement, err error) {
+
+	tkn := newTokeniser()
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return p.err(branch, err.Error())
+			}
+
+			if len(tokens) == 0 || tokens[0].kind == tokenCommentStart {
+				continue
+			}
+
+			if tokens[0].kind == tokenFunctionCall && tokens[0].content == builtinMixinInclude {
+
+				fileTokens, _ := splitIncludeArguments(tokens[1:])
+
+				if names, err := p.extractValuesFromArgTokens(branch, fileTokens, p.rootScope); err == nil {
+					statements = append(statements, includeStatement{branch, names})
+				}
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err = walk(lines)
+
+	return
+}
+
+// A provision collects what an included file provides to the file including
+// it: the names it declares and whether it writes any output. The files are
+// those whose names have been collected, and which aren't references.
+type provision struct {
+	names  map[string]bool
+	files  map[string]bool
+	output bool
+}
+
+func (pr provision) used(references map[string]map[string]bool) bool {
+
+	for path, names := range references {
+
+		if pr.files[path] {
+			continue
+		}
+
+		for name := range pr.names {
+			if names[name] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *parser) provides(fileName string, pr *provision) error {
+
+	if pr.files[fileName] {
+		return nil
+	}
+
+	pr.files[fileName] = true
+
+	lines, err := p.scanFile(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	exports, err := p.exportsFromTree(lines, newTokeniser())
+
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		pr.names[export.Name] = true
+	}
+
+	tkn := newTokeniser()
+
+	for _, branch := range lines {
+
+		tokens, err := tkn.tokenise(branch)
+
+		if err != nil {
+			return p.err(branch, err.Error())
+		}
+
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch tokens[0].kind {
+
+		case tokenCommentStart, tokenCommentEnd, tokenLineComment, tokenExport, tokenMetadata:
+			// Nothing provided
+
+		case tokenMixinDeclaration, tokenVariableAssignment, tokenVariableDeclaration, tokenConditionalVariableAssignment:
+			if len(exports) == 0 {
+				pr.names[tokens[0].content] = true
+			}
+
+		case tokenFunctionCall:
+
+			if tokens[0].content != builtinMixinInclude {
+				pr.output = true
+				continue
+			}
+
+			fileTokens, _ := splitIncludeArguments(tokens[1:])
+			names, err := p.extractValuesFromArgTokens(branch, fileTokens, p.rootScope)
+
+			if err != nil {
+
+				// An include that can't be followed might provide anything
+				pr.output = true
+				continue
+			}
+
+			for _, name := range names {
+
+				paths, err := p.resolveInclude(name, branch)
+
+				if err != nil {
+					return p.err(branch, err.Error())
+				}
+
+				// The names of nested includes are only visible if they're
+				// exported
+				nested := provision{names: make(map[string]bool), files: pr.files}
+
+				for _, path := range paths {
+					if err := p.provides(path, &nested); err != nil {
+						return err
+					}
+				}
+
+				pr.output = pr.output || nested.output
+
+				if len(exports) == 0 {
+					for name := range nested.names {
+						pr.names[name] = true
+					}
+				}
+			}
+
+		default:
+			pr.output = true
+		}
+	}
+
+	return nil
+}
+
+// referencedNames collects the names of the mixins called and variables used
+// in a file, outside comment blocks.
+func referencedNames(lines scannerTree) (map[string]bool, error) {
+
+	names := make(map[string]bool)
+	tkn := newTokeniser()
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			tokens, err := tkn.tokenise(branch)
+
+			if err != nil {
+				return fmt.Errorf("[%s] %s", branch, err)
+			}
+
+			if len(tokens) == 0 || tokens[0].kind == tokenCommentStart || tokens[0].kind == tokenLineComment || tokens[0].kind == tokenMetadata {
+				continue
+			}
+
+			if tokens[0].kind == tokenFunctionCall {
+				names[tokens[0].content] = true
+			}
+
+			for _, t := range tokens {
+
+				if t.kind == tokenVariable {
+					names[t.content] = true
+				}
+
+				for _, m := range variableReferenceMatcher.FindAllStringSubmatch(t.content, -1) {
+					names[m[1]] = true
+				}
+			}
+
+			// Exports refer to the names they export
+			if tokens[0].kind == tokenExport {
+				names[tokens[0].content] = true
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return names, walk(lines)
+}
+
+/*
+RemoveIncludes removes the given arguments from the include statements in SCL
+source, as reported by UnusedIncludes(), and removes statements left with no
+arguments altogether.
+*/
+func RemoveIncludes(src []byte, fileName string, unused []UnusedInclude) ([]byte, error) {
+
+	remove := make(map[int]map[string]bool)
+
+	for _, u := range unused {
+		if u.File == fileName {
+
+			if remove[u.Line] == nil {
+				remove[u.Line] = make(map[string]bool)
+			}
+
+			remove[u.Line][u.Name] = true
+		}
+	}
+
+	tree, err := newScanner(bytes.NewReader(src), fileName).scan()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't scan %s: %s", fileName, err)
+	}
+
+	lines := strings.Split(string(src), "\n")
+	deleted := make(map[int]bool)
+	tkn := newTokeniser()
+
+	var walk func(tree scannerTree) error
+
+	walk = func(tree scannerTree) error {
+
+		for _, branch := range tree {
+
+			if names := remove[branch.line]; names != nil {
+
+				tokens, err := tkn.tokenise(branch)
+
+				if err != nil {
+					return fmt.Errorf("[%s] %s", branch, err)
+				}
+
+				if len(tokens) == 0 || tokens[0].kind != tokenFunctionCall || tokens[0].content != builtinMixinInclude {
+					return fmt.Errorf("Can't remove includes: %s isn't an include statement", branch)
+				}
+
+				fileTokens, paramTokens := splitIncludeArguments(tokens[1:])
+
+				var args []string
+
+				for _, t := range fileTokens {
+					if t.kind != tokenLiteral || !names[strings.Trim(t.content, `"'`)] {
+						args = append(args, tokenArgument(t))
+					}
+				}
+
+				if len(args) == 0 {
+					deleted[branch.line-1] = true
+					continue
+				}
+
+				for i := 0; i < len(paramTokens); i += 2 {
+					args = append(args, fmt.Sprintf("$%s = %s", paramTokens[i].content, paramTokens[i+1].content))
+				}
+
+				lines[branch.line-1] = fmt.Sprintf("%s%s(%s)", indentation(lines[branch.line-1]), builtinMixinInclude, strings.Join(args, ", "))
+			}
+
+			if err := walk(branch.children); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(tree); err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(lines))
+
+	for i, line := range lines {
+		if !deleted[i] {
+			out = append(out, line)
+		}
+	}
+
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// tokenArgument returns the source of a function argument.
+func tokenArgument(t token) string {
+
+	if t.kind == tokenVariable {
+		return "$" + t.content
+	}
+
+	return t.content
+}
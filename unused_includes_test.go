@@ -0,0 +1,45 @@
+package scl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AParserCanFindUnusedIncludes(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("lib-a.scl", []byte("@a()\n    a = 1\n"))
+	p.AddVirtualFile("lib-b.scl", []byte("@b()\n    b = 1\n"))
+	p.AddVirtualFile("lib-output.scl", []byte("written = 1\n"))
+	p.AddVirtualFile("lib-variable.scl", []byte("$v = \"x\"\n"))
+	p.AddVirtualFile("lib-exports.scl", []byte("/export c\n@c()\n    c = 1\n@a()\n    a = 2\n"))
+
+	src := `include("lib-a", "lib-b")
+include("lib-output")
+include("lib-variable")
+include("lib-exports")
+
+a()
+value = "${v}"
+`
+
+	p.AddVirtualFile("main.scl", []byte(src))
+
+	unused, err := p.UnusedIncludes("main.scl")
+	require.Nil(t, err)
+	require.Equal(t, []UnusedInclude{
+		{File: "main.scl", Line: 1, Name: "lib-b"},
+		{File: "main.scl", Line: 4, Name: "lib-exports"},
+	}, unused)
+
+	out, err := RemoveIncludes([]byte(src), "main.scl", unused)
+	require.Nil(t, err)
+	require.Equal(t, `include("lib-a")
+include("lib-output")
+include("lib-variable")
+
+a()
+value = "${v}"
+`, string(out))
+}
@@ -0,0 +1,123 @@
+package scl
+
+/*
+An Option adjusts a Config before a Parser is created from it.
+*/
+type Option func(*Config)
+
+/*
+WithFileSystem sets the FileSystem that all files and includes are read from.
+Only a Reader is required.
+*/
+func WithFileSystem(fs Reader) Option {
+	return func(c *Config) {
+		c.FileSystem = fs
+	}
+}
+
+/*
+WithParam sets a variable in the root scope. The value is used as it is, so
+strings must be quoted.
+*/
+func WithParam(name, value string) Option {
+	return func(c *Config) {
+
+		params := make(map[string]string, len(c.Params)+1)
+
+		for k, v := range c.Params {
+			params[k] = v
+		}
+
+		params[name] = value
+		c.Params = params
+	}
+}
+
+/*
+WithEnvironment imports a snapshot of environment variables as string params,
+before any params set by WithParam. Version 1's SnapshotEnvironment() takes a
+snapshot of the process environment.
+*/
+func WithEnvironment(e Environment) Option {
+	return func(c *Config) {
+		c.Environment = e
+	}
+}
+
+/*
+WithIncludePaths adds paths to search for included files, after any already
+in the Config.
+*/
+func WithIncludePaths(paths ...string) Option {
+	return func(c *Config) {
+		c.IncludePaths = append(append([]string{}, c.IncludePaths...), paths...)
+	}
+}
+
+/*
+WithWorkspace sets the workspace libraries to use instead of vendored copies.
+*/
+func WithWorkspace(w Workspace) Option {
+	return func(c *Config) {
+		c.Workspace = w
+	}
+}
+
+/*
+WithCaseInsensitiveIncludes makes includes match files whatever the case of
+their names.
+*/
+func WithCaseInsensitiveIncludes() Option {
+	return func(c *Config) {
+		c.CaseInsensitive = true
+	}
+}
+
+/*
+WithVirtualFile adds a file held in memory, which can be parsed and included
+like any other. It takes precedence over a file of the same name in the
+FileSystem.
+*/
+func WithVirtualFile(name string, content []byte) Option {
+	return func(c *Config) {
+
+		files := make(map[string][]byte, len(c.VirtualFiles)+1)
+
+		for k, v := range c.VirtualFiles {
+			files[k] = v
+		}
+
+		files[name] = content
+		c.VirtualFiles = files
+	}
+}
+
+/*
+WithPreprocessor sets a function that transforms the raw content of every file
+before it's parsed.
+*/
+func WithPreprocessor(fn Preprocessor) Option {
+	return func(c *Config) {
+		c.Preprocessor = fn
+	}
+}
+
+/*
+WithPostprocessor sets a function that transforms the output before it's
+returned by String() or written by WriteTo().
+*/
+func WithPostprocessor(fn Postprocessor) Option {
+	return func(c *Config) {
+		c.Postprocessor = fn
+	}
+}
+
+/*
+WithOutputFormat sets the layout of the output, such as its indentation and
+the width that long lists are wrapped at.
+*/
+func WithOutputFormat(format OutputFormat) Option {
+	return func(c *Config) {
+		c.OutputFormat = format
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Package scl is version 2 of the SCL parser API. It's a thin layer over the
+original package which replaces the setters on the Parser with a single
+Config, adjusted by functional options, and takes a context for parsing:
+
+	parser, err := scl.New(scl.Config{},
+		scl.WithIncludePaths("vendor"),
+		scl.WithParam("environment", `"production"`),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := parser.Parse(ctx, "main.scl"); err != nil {
+		return err
+	}
+
+New capabilities are added as Config fields and options, rather than as
+methods on the Parser. The types shared with version 1 are aliases, so values
+can be passed between the two freely.
+*/
+package scl
+
+import (
+	"context"
+	"io"
+
+	"github.com/hashicorp/hcl"
+
+	v1 "github.com/homemade/scl"
+)
+
+type (
+	Config         = v1.Config
+	FileSystem     = v1.FileSystem
+	Reader         = v1.Reader
+	Glober         = v1.Glober
+	Writer         = v1.Writer
+	Watcher        = v1.Watcher
+	Event          = v1.Event
+	Preprocessor   = v1.Preprocessor
+	Postprocessor  = v1.Postprocessor
+	Workspace      = v1.Workspace
+	MixinDocs      = v1.MixinDocs
+	ExportDocs     = v1.ExportDocs
+	FileMetadata   = v1.FileMetadata
+	CoverageBlocks = v1.CoverageBlocks
+	Inputs         = v1.Inputs
+	OutputFormat   = v1.OutputFormat
+	Environment    = v1.Environment
+	UnusedInclude  = v1.UnusedInclude
+)
+
+/*
+A Parser transforms SCL into HCL. See the version 1 Parser for the details of
+how files, variables and includes interact.
+*/
+type Parser interface {
+	Parse(ctx context.Context, fileName string) error
+	Documentation(fileName string) (MixinDocs, error)
+	Exports(fileName string) (ExportDocs, error)
+	Metadata(fileName string) (FileMetadata, error)
+	Includes(fileName string) ([]string, error)
+	UnusedIncludes(fileName string) ([]UnusedInclude, error)
+	Coverage() CoverageBlocks
+	Statements(fileName string) (CoverageBlocks, error)
+	Inputs() Inputs
+	Environment() Environment
+	Warnings() []string
+	Watch(ctx context.Context, path string) (<-chan Event, error)
+	WriteTo(w io.Writer) (int64, error)
+	String() string
+}
+
+type parser struct {
+	v1.Parser
+}
+
+/*
+New creates a Parser from a Config, after applying any options to it. The
+Config is copied, so the options never change the caller's value.
+*/
+func New(config Config, options ...Option) (Parser, error) {
+
+	for _, option := range options {
+		option(&config)
+	}
+
+	p, err := v1.NewParserFromConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parser{p}, nil
+}
+
+func (p parser) Parse(ctx context.Context, fileName string) error {
+	return p.Parser.ParseContext(ctx, fileName)
+}
+
+/*
+DecodeFile parses the given file and decodes the result into the structure
+given by `out`.
+*/
+func DecodeFile(ctx context.Context, out interface{}, fileName string, options ...Option) error {
+
+	p, err := New(Config{}, options...)
+
+	if err != nil {
+		return err
+	}
+
+	if err := p.Parse(ctx, fileName); err != nil {
+		return err
+	}
+
+	return hcl.Decode(out, p.String())
+}
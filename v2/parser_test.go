@@ -0,0 +1,58 @@
+package scl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AParserCanBeCreatedWithOptions(t *testing.T) {
+
+	config := Config{IncludePaths: []string{"a"}}
+
+	p, err := New(config, WithIncludePaths("../fixtures/valid"), WithParam("myVar", "1"))
+	require.Nil(t, err)
+	require.NotNil(t, p)
+
+	// Options are applied to a copy of the Config
+	require.Equal(t, []string{"a"}, config.IncludePaths)
+	require.Nil(t, config.Params)
+
+	require.Nil(t, p.Parse(context.Background(), "../fixtures/valid/variables.scl"))
+	require.Contains(t, p.String(), "1")
+}
+
+func Test_AParserStopsWhenItsContextIsCancelled(t *testing.T) {
+
+	p, err := New(Config{})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = p.Parse(ctx, "../fixtures/valid/basic.scl")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), context.Canceled.Error())
+}
+
+func Test_AFileCanBeDecodedWithOptions(t *testing.T) {
+
+	var out map[string]interface{}
+
+	require.Nil(t, DecodeFile(context.Background(), &out, "../fixtures/valid/decode.scl"))
+	require.Equal(t, "1", out["value0"])
+}
+
+func Test_AParserListsUnusedIncludes(t *testing.T) {
+
+	p, err := New(Config{},
+		WithVirtualFile("lib.scl", []byte("@unused()\n    a = 1")),
+		WithVirtualFile("main.scl", []byte(`include("lib.scl")`+"\nb = 2")),
+	)
+	require.Nil(t, err)
+
+	unused, err := p.UnusedIncludes("main.scl")
+	require.Nil(t, err)
+	require.Equal(t, []UnusedInclude{{File: "main.scl", Line: 1, Name: "lib.scl"}}, unused)
+}
@@ -0,0 +1,237 @@
+package scl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A virtualFileSystem overlays files held in memory on top of another
+// filesystem. Virtual files take precedence over real files with the same
+// name, and are included in glob results.
+type virtualFileSystem struct {
+	base    Reader
+	files   map[string][]byte
+	created time.Time
+	lock    sync.Mutex
+	watches []*virtualWatch
+}
+
+// A virtualWatch queues the changes to virtual files for one call to Watch,
+// so that adding a file never waits for the watcher to receive the event.
+type virtualWatch struct {
+	path   string
+	queue  []Event
+	notify chan struct{}
+}
+
+func newVirtualFileSystem(base Reader) *virtualFileSystem {
+	return &virtualFileSystem{
+		base:    base,
+		files:   make(map[string][]byte),
+		created: time.Now(),
+	}
+}
+
+func (v *virtualFileSystem) add(name string, content []byte) {
+
+	name = filepath.Clean(name)
+	e := Event{Path: name, Op: EventCreate}
+
+	if v.has(name) {
+		e.Op = EventWrite
+	}
+
+	v.files[name] = append([]byte{}, content...)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for _, w := range v.watches {
+		if w.covers(name) {
+
+			w.queue = append(w.queue, e)
+
+			select {
+			case w.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (v *virtualFileSystem) has(path string) bool {
+	_, ok := v.files[filepath.Clean(path)]
+	return ok
+}
+
+func (v *virtualFileSystem) ReadCloser(path string) (io.ReadCloser, time.Time, error) {
+
+	if content, ok := v.files[filepath.Clean(path)]; ok {
+		return ioutil.NopCloser(bytes.NewReader(content)), v.created, nil
+	}
+
+	return v.base.ReadCloser(path)
+}
+
+func (v *virtualFileSystem) RealPath(path string) (string, error) {
+
+	if v.has(path) {
+		return filepath.Clean(path), nil
+	}
+
+	return realPath(v.base, path), nil
+}
+
+func (v *virtualFileSystem) Glob(pattern string) ([]string, error) {
+
+	seen := make(map[string]bool)
+	pattern = filepath.Clean(pattern)
+
+	for name := range v.files {
+
+		ok, err := filepath.Match(pattern, name)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't expand %s: %s", pattern, err)
+		}
+
+		if ok {
+			seen[name] = true
+		}
+	}
+
+	// A filesystem that can't expand wildcards is only an error if no
+	// virtual files matched either
+	paths, err := glob(v.base, pattern)
+
+	if err != nil && len(seen) == 0 {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		seen[path] = true
+	}
+
+	matches := make([]string, 0, len(seen))
+
+	for path := range seen {
+		matches = append(matches, path)
+	}
+
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+/*
+Watch reports virtual files added at or beneath a path, and, if the base
+filesystem is a Watcher, changes to the real files there too. A path with no
+real files can be watched for virtual files, even if the base filesystem can't
+watch it.
+*/
+func (v *virtualFileSystem) Watch(ctx context.Context, path string) (<-chan Event, error) {
+
+	w := &virtualWatch{path: filepath.Clean(path), notify: make(chan struct{}, 1)}
+
+	var base <-chan Event
+
+	if watcher, ok := v.base.(Watcher); ok {
+
+		var err error
+
+		if base, err = watcher.Watch(ctx, path); err != nil && !v.hasBeneath(w.path) {
+			return nil, err
+		}
+	}
+
+	v.lock.Lock()
+	v.watches = append(v.watches, w)
+	v.lock.Unlock()
+
+	events := make(chan Event)
+
+	go func() {
+
+		defer close(events)
+		defer v.unwatch(w)
+
+		for {
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case e, ok := <-base:
+
+				if !ok {
+					base = nil
+					continue
+				}
+
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-w.notify:
+
+				v.lock.Lock()
+				queue := w.queue
+				w.queue = nil
+				v.lock.Unlock()
+
+				for _, e := range queue {
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (v *virtualFileSystem) unwatch(w *virtualWatch) {
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for i, watch := range v.watches {
+		if watch == w {
+			v.watches = append(v.watches[:i], v.watches[i+1:]...)
+			return
+		}
+	}
+}
+
+// hasBeneath reports whether any virtual file is at or beneath a path.
+func (v *virtualFileSystem) hasBeneath(path string) bool {
+
+	w := virtualWatch{path: path}
+
+	for name := range v.files {
+		if w.covers(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// covers reports whether a change to the named file should be reported by
+// the watch.
+func (w *virtualWatch) covers(name string) bool {
+	return w.path == "." || name == w.path || strings.HasPrefix(name, w.path+string(filepath.Separator))
+}
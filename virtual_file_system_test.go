@@ -0,0 +1,76 @@
+package scl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AParserCanIncludeVirtualFiles(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("generated/params.scl", []byte(`$region = "eu-west-1"`))
+	p.AddVirtualFile("main.scl", []byte("include(\"generated/*\")\nregion = $region"))
+
+	require.Nil(t, p.Parse("main.scl"))
+	require.Equal(t, `region = "eu-west-1"`, p.String())
+
+	includes, err := p.Includes("main.scl")
+	require.Nil(t, err)
+	require.Equal(t, []string{"generated/params.scl"}, includes)
+}
+
+func Test_AVirtualFileTakesPrecedenceOverTheFileSystem(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("fixtures/valid/simple-mixin.scl", []byte("@simpleMixin($var)\n    virtual = $var"))
+
+	require.Nil(t, p.Parse("fixtures/valid/import.scl"))
+	require.Contains(t, p.String(), `virtual = "this is from simpleMixin"`)
+
+	paths, err := p.fs.(Glober).Glob("fixtures/valid/simple-*.scl")
+	require.Nil(t, err)
+	require.Equal(t, []string{"fixtures/valid/simple-mixin.scl"}, paths)
+}
+
+func Test_AVirtualFileSystemCanWatchForAddedFiles(t *testing.T) {
+
+	p := newMockParser(t)
+	p.AddVirtualFile("generated/a.scl", []byte("a = 1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// There's no generated directory on disk, so only virtual files are
+	// reported
+	events, err := p.Watch(ctx, "generated")
+	require.Nil(t, err)
+
+	next := func() Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("No event received")
+		}
+		return Event{}
+	}
+
+	p.AddVirtualFile("other.scl", []byte("b = 1"))
+	p.AddVirtualFile("generated/b.scl", []byte("b = 1"))
+	p.AddVirtualFile("generated/a.scl", []byte("a = 2"))
+
+	require.Equal(t, Event{Path: "generated/b.scl", Op: EventCreate}, next())
+	require.Equal(t, Event{Path: "generated/a.scl", Op: EventWrite}, next())
+
+	cancel()
+
+	select {
+	case _, open := <-events:
+		require.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("The watch didn't end")
+	}
+}
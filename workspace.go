@@ -0,0 +1,103 @@
+package scl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+)
+
+/*
+WorkspaceFileName is the conventional name of a workspace file. Tools look for
+it in the current directory and each of its parents, in the same way that the
+go tool looks for go.work.
+*/
+const WorkspaceFileName = "scl.work"
+
+/*
+A Workspace maps library names to local directories, so that libraries that
+are developed together can include each other's working copies rather than a
+vendored copy. A workspace file is written in HCL:
+
+	library "github.com/myorg/network" {
+	  path = "../network"
+	}
+
+Paths are relative to the directory containing the workspace file.
+*/
+type Workspace map[string]string
+
+/*
+LoadWorkspace reads a workspace file from the given FileSystem.
+*/
+func LoadWorkspace(fs Reader, path string) (Workspace, error) {
+
+	f, _, err := fs.ReadCloser(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read %s: %s", path, err)
+	}
+
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read %s: %s", path, err)
+	}
+
+	config := struct {
+		Libraries map[string]struct {
+			Path string `hcl:"path"`
+		} `hcl:"library"`
+	}{}
+
+	if err := hcl.Decode(&config, string(content)); err != nil {
+		return nil, fmt.Errorf("Can't decode %s: %s", path, err)
+	}
+
+	w := Workspace{}
+
+	for name, library := range config.Libraries {
+
+		if library.Path == "" {
+			return nil, fmt.Errorf("Can't decode %s: library %s has no path", path, name)
+		}
+
+		dir := library.Path
+
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+
+		w[strings.TrimSuffix(name, "/")] = dir
+	}
+
+	return w, nil
+}
+
+// resolve maps an include name onto a workspace directory, if any library in
+// the workspace is a prefix of the name. The longest match wins.
+func (w Workspace) resolve(name string) (path string, ok bool) {
+
+	libraries := make([]string, 0, len(w))
+
+	for library := range w {
+		libraries = append(libraries, library)
+	}
+
+	sort.Slice(libraries, func(i, j int) bool {
+		return len(libraries[i]) > len(libraries[j])
+	})
+
+	for _, library := range libraries {
+		if strings.HasPrefix(name, library+"/") {
+			return filepath.Join(w[library], strings.TrimPrefix(name, library+"/")), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,33 @@
+package scl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AWorkspaceCanBeLoaded(t *testing.T) {
+
+	w, err := LoadWorkspace(NewDiskSystem(), "fixtures/workspace/scl.work")
+	require.Nil(t, err)
+	require.Equal(t, Workspace{"example.com/shared": "fixtures/workspace/shared"}, w)
+
+	_, err = LoadWorkspace(NewDiskSystem(), "fixtures/workspace/missing.work")
+	require.Equal(t, fmt.Errorf("Can't read fixtures/workspace/missing.work: open fixtures/workspace/missing.work: no such file or directory"), err)
+}
+
+func Test_AParserIncludesWorkspaceLibrariesFromTheirWorkingCopies(t *testing.T) {
+
+	w, err := LoadWorkspace(NewDiskSystem(), "fixtures/workspace/scl.work")
+	require.Nil(t, err)
+
+	p := newMockParser(t)
+
+	for name, path := range w {
+		p.AddWorkspaceLibrary(name, path)
+	}
+
+	require.Nil(t, p.Parse("fixtures/workspace/main.scl"))
+	require.Equal(t, `greeting = "workspace"`, p.String())
+}